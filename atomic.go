@@ -0,0 +1,420 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+const (
+	// AtomicExtension is the URI of the Atomic Operations extension, given in
+	// the "ext" parameter of the media type of atomic requests and responses.
+	//
+	// https://jsonapi.org/ext/atomic/
+	AtomicExtension = "https://jsonapi.org/ext/atomic"
+
+	// OperationAdd, OperationUpdate and OperationRemove are the codes of the
+	// "op" member of an atomic operation.
+	OperationAdd    = "add"
+	OperationUpdate = "update"
+	OperationRemove = "remove"
+
+	atomicOperationsMember = "atomic:operations"
+	atomicResultsMember    = "atomic:results"
+)
+
+// Operations is a decoded Atomic Operations request, as returned by
+// UnmarshalOperations. The operations are meant to be performed in order;
+// as each one adds a resource, Resolve its lid so that the later operations
+// refer to the resource by its new id.
+type Operations struct {
+	Operations []*Operation
+
+	opts *unmarshalOptions
+	// lids maps the type and lid of each resolved resource to its id.
+	lids map[string]string
+}
+
+// Operation is a single operation of an Atomic Operations request.
+type Operation struct {
+	// Op is one of OperationAdd, OperationUpdate or OperationRemove.
+	Op string `json:"op"`
+	// Ref or Href, if given, is the target of the operation.
+	Ref  *OperationRef `json:"ref,omitempty"`
+	Href string        `json:"href,omitempty"`
+	// Data is the raw primary data of the operation; see Model and Linkage.
+	Data json.RawMessage `json:"data,omitempty"`
+	Meta *Meta           `json:"meta,omitempty"`
+
+	operations *Operations
+	// pointer is the JSON Pointer to the operation within the request.
+	pointer string
+	// dataType and dataLid identify the resource object of Data.
+	dataType string
+	dataLid  string
+}
+
+// OperationRef is the "ref" member of an operation, which targets a resource,
+// or one of its relationships, by type and either id or lid.
+type OperationRef struct {
+	Type string `json:"type"`
+	// ID is set by Resolve for a ref given by lid.
+	ID           string `json:"id,omitempty"`
+	Lid          string `json:"lid,omitempty"`
+	Relationship string `json:"relationship,omitempty"`
+}
+
+// resourceIdentifier is a resource identifier object, which may identify the
+// resource by its lid.
+type resourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	Lid  string `json:"lid,omitempty"`
+	Meta *Meta  `json:"meta,omitempty"`
+}
+
+// UnmarshalOperations reads an Atomic Operations request:
+//
+//	ops, err := jsonapi.UnmarshalOperations(r.Body)
+//	if err != nil {
+//		// a malformed request; err is ErrorObjects with a 400 status
+//	}
+//	for _, op := range ops.Operations {
+//		switch op.Op {
+//		case jsonapi.OperationAdd:
+//			model, err := op.Model()
+//			// ...persist model...
+//			op.Resolve(id)
+//		}
+//	}
+//
+// Operations that are malformed, e.g. an "add" without "data", are returned as
+// ErrorObjects with a source pointer to the operation, such as
+// "/atomic:operations/1/data". The UnmarshalOptions apply to Model as they do
+// to UnmarshalPayload; models are of the types given by UnmarshalTypes or
+// Register.
+//
+// See https://jsonapi.org/ext/atomic/
+func UnmarshalOperations(in io.Reader, opts ...UnmarshalOption) (*Operations, error) {
+	doc := struct {
+		Operations []*Operation `json:"atomic:operations"`
+	}{}
+	if err := json.NewDecoder(in).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Operations == nil {
+		return nil, ErrorObjects{newOperationError("/"+atomicOperationsMember, "The request must have an atomic:operations array")}
+	}
+
+	ops := &Operations{
+		Operations: doc.Operations,
+		opts:       newUnmarshalOptions(opts),
+		lids:       map[string]string{},
+	}
+
+	var errs ErrorObjects
+	for i, op := range ops.Operations {
+		pointer := fmt.Sprintf("/%s/%d", atomicOperationsMember, i)
+		if op == nil {
+			errs = append(errs, newOperationError(pointer, "An operation must be an object"))
+			continue
+		}
+		op.operations = ops
+		op.pointer = pointer
+		errs = append(errs, op.validate()...)
+
+		if data := bytes.TrimSpace(op.Data); len(data) > 0 && data[0] == '{' {
+			ident := new(resourceIdentifier)
+			if err := json.Unmarshal(data, ident); err == nil {
+				op.dataType, op.dataLid = ident.Type, ident.Lid
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return ops, nil
+}
+
+// validate returns an error for each member of op that is missing or invalid.
+func (op *Operation) validate() ErrorObjects {
+	var errs ErrorObjects
+	hasData := len(op.Data) > 0
+
+	switch op.Op {
+	case OperationAdd, OperationUpdate:
+		if !hasData {
+			errs = append(errs, newOperationError(op.pointer+"/data", fmt.Sprintf("An %q operation must have data", op.Op)))
+		} else if !op.IsRelationship() && jsonType(op.Data) != "object" {
+			// only the linkage of a relationship may be null
+			errs = append(errs, newOperationError(op.pointer+"/data", fmt.Sprintf("The data of an %q operation must be a resource object", op.Op)))
+		}
+	case OperationRemove:
+		if op.Ref == nil && op.Href == "" {
+			errs = append(errs, newOperationError(op.pointer, "A \"remove\" operation must have a ref or href"))
+		}
+	default:
+		errs = append(errs, newOperationError(op.pointer+"/op", fmt.Sprintf("%q is not a valid operation code", op.Op)))
+	}
+
+	if op.Ref != nil {
+		if op.Href != "" {
+			errs = append(errs, newOperationError(op.pointer, "An operation must not have both a ref and an href"))
+		}
+		if op.Ref.Type == "" {
+			errs = append(errs, newOperationError(op.pointer+"/ref/type", "A ref must have a type"))
+		}
+		if op.Ref.ID == "" && op.Ref.Lid == "" {
+			errs = append(errs, newOperationError(op.pointer+"/ref", "A ref must have an id or lid"))
+		}
+	}
+
+	return errs
+}
+
+// IsRelationship reports whether op targets a relationship rather than a
+// resource, in which case its data is resource linkage; see Linkage.
+func (op *Operation) IsRelationship() bool {
+	return op.Ref != nil && op.Ref.Relationship != ""
+}
+
+// Model unmarshals the resource object of an "add" or "update" operation into
+// a new model of the type registered for its type. Resource linkage given by
+// the lid of a resolved resource is unmarshaled with its id, as is the
+// resource itself when an "update" refers to it by lid.
+//
+// Errors are *UnmarshalErrors with a pointer into the operation, e.g.
+// "/atomic:operations/2/data/attributes/title"; their ErrorObject method gives
+// the error object to respond with.
+func (op *Operation) Model() (interface{}, error) {
+	if op.IsRelationship() || len(op.Data) == 0 {
+		return nil, &UnmarshalError{
+			Pointer: op.pointer + "/data",
+			Err:     errors.New("the operation does not have a resource object"),
+		}
+	}
+
+	r := new(rawNode)
+	if err := json.Unmarshal(op.Data, &r); err != nil || r == nil {
+		return nil, &UnmarshalError{
+			Pointer:  op.pointer + "/data",
+			Expected: "object",
+			Actual:   jsonType(op.Data),
+			Err:      errors.New("the operation does not have a resource object"),
+		}
+	}
+	n := r.node()
+	n.pointer = op.pointer + "/data"
+
+	if op.Op != OperationAdd && n.ID == "" && op.dataLid != "" {
+		id, err := op.operations.resolveLid(op.dataType, op.dataLid, n.pointer)
+		if err != nil {
+			return nil, err
+		}
+		n.ID = id
+	}
+
+	for name, rel := range n.Relationships {
+		raw, err := op.operations.resolveRelationship(rel, n.pointer+"/relationships/"+escapeJSONPointer(name))
+		if err != nil {
+			return nil, err
+		}
+		n.Relationships[name] = raw
+	}
+
+	o := op.operations.opts
+	model, err := o.newModel(anyModelType, n)
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshalResource(n, model, nil, o); err != nil {
+		return nil, err
+	}
+	return model.Interface(), nil
+}
+
+// Linkage returns the resource identifiers of the data of an operation that
+// targets a relationship, with the lids of resolved resources replaced by
+// their ids.
+func (op *Operation) Linkage() (*Linkage, error) {
+	raw, err := op.operations.resolveLinkage(op.Data, op.pointer+"/data")
+	if err != nil {
+		return nil, err
+	}
+
+	linkage := new(Linkage)
+	if data := bytes.TrimSpace(raw); len(data) > 0 && data[0] == '[' {
+		linkage.IsMany = true
+		err = json.Unmarshal(data, &linkage.Data)
+	} else if len(data) > 0 {
+		var n *Node
+		if err = json.Unmarshal(data, &n); err == nil && n != nil {
+			linkage.Data = []*Node{n}
+		}
+	}
+	if err != nil {
+		return nil, &UnmarshalError{Pointer: op.pointer + "/data", Err: err}
+	}
+	return linkage, nil
+}
+
+// Resolve records id as the id of the resource added by op, which the request
+// identified by its lid. The refs and resource linkage of the later operations
+// that use the lid then refer to id.
+func (op *Operation) Resolve(id string) {
+	if op.dataLid == "" {
+		return
+	}
+	ops := op.operations
+	ops.lids[op.dataType+annotationSeperator+op.dataLid] = id
+
+	for _, later := range ops.Operations {
+		if ref := later.Ref; ref != nil && ref.ID == "" && ref.Type == op.dataType && ref.Lid == op.dataLid {
+			ref.ID = id
+		}
+	}
+}
+
+// resolveLid returns the id that the lid of a resource of type typ was
+// resolved to; pointer locates the resource identifier.
+func (ops *Operations) resolveLid(typ, lid, pointer string) (string, error) {
+	id, ok := ops.lids[typ+annotationSeperator+lid]
+	if !ok {
+		return "", &UnmarshalError{
+			Pointer: pointer + "/lid",
+			Err:     fmt.Errorf("lid %q does not refer to a resource of type %q added by an earlier operation", lid, typ),
+		}
+	}
+	return id, nil
+}
+
+// resolveRelationship resolves the lids of the linkage of the relationship
+// object rel, located by pointer.
+func (ops *Operations) resolveRelationship(rel interface{}, pointer string) (json.RawMessage, error) {
+	raw, err := rawValue(rel)
+	if err != nil {
+		return nil, err
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil || members["data"] == nil {
+		// left to be reported by unmarshaling
+		return raw, nil
+	}
+
+	data, err := ops.resolveLinkage(members["data"], pointer+"/data")
+	if err != nil {
+		return nil, err
+	}
+	members["data"] = data
+	return json.Marshal(members)
+}
+
+// resolveLinkage replaces the lids of the resource identifiers of the raw
+// resource linkage, located by pointer, with their resolved ids.
+func (ops *Operations) resolveLinkage(raw json.RawMessage, pointer string) (json.RawMessage, error) {
+	data := bytes.TrimSpace(raw)
+	if len(data) == 0 || data[0] == 'n' {
+		return raw, nil
+	}
+
+	many := data[0] == '['
+	var idents []*resourceIdentifier
+	if many {
+		if err := json.Unmarshal(data, &idents); err != nil {
+			return nil, &UnmarshalError{Pointer: pointer, Err: err}
+		}
+	} else {
+		ident := new(resourceIdentifier)
+		if err := json.Unmarshal(data, ident); err != nil {
+			return nil, &UnmarshalError{Pointer: pointer, Err: err}
+		}
+		idents = []*resourceIdentifier{ident}
+	}
+
+	resolved := false
+	for i, ident := range idents {
+		if ident == nil || ident.ID != "" || ident.Lid == "" {
+			continue
+		}
+		identPointer := pointer
+		if many {
+			identPointer = fmt.Sprintf("%s/%d", pointer, i)
+		}
+		id, err := ops.resolveLid(ident.Type, ident.Lid, identPointer)
+		if err != nil {
+			return nil, err
+		}
+		ident.ID, ident.Lid = id, ""
+		resolved = true
+	}
+	if !resolved {
+		return raw, nil
+	}
+
+	if many {
+		return json.Marshal(idents)
+	}
+	return json.Marshal(idents[0])
+}
+
+func newOperationError(pointer, detail string) *ErrorObject {
+	return &ErrorObject{
+		Title:  "Invalid operation",
+		Detail: detail,
+		Status: "400",
+		Source: &ErrorSource{Pointer: pointer},
+	}
+}
+
+// OperationResult is the result of a single operation of an Atomic
+// Operations request.
+type OperationResult struct {
+	// Data is the model of the resource added or updated by the operation, a
+	// struct pointer, or nil if the operation has no data to return.
+	Data interface{}
+	Meta *Meta
+}
+
+// resultNode is an element of the "atomic:results" array.
+type resultNode struct {
+	Data *Node `json:"data,omitempty"`
+	Meta *Meta `json:"meta,omitempty"`
+}
+
+// MarshalResults writes the response to an Atomic Operations request, with a
+// result for each of its operations, in order. The MarshalOptions apply to the
+// data of each result as they do to MarshalPayload, except that no related
+// resources are sideloaded.
+func MarshalResults(w io.Writer, results []*OperationResult, opts ...MarshalOption) error {
+	o := newMarshalOptions(opts)
+
+	nodes := make([]*resultNode, len(results))
+	for i, result := range results {
+		nodes[i] = new(resultNode)
+		if result == nil {
+			continue
+		}
+		nodes[i].Meta = result.Meta
+
+		if result.Data == nil {
+			continue
+		}
+		vals := reflect.ValueOf(result.Data)
+		if vals.Kind() != reflect.Ptr || reflect.Indirect(vals).Kind() != reflect.Struct {
+			return ErrUnexpectedType
+		}
+		node, err := visitModelNode(result.Data, nil, true, o, includeTree{})
+		if err != nil {
+			return err
+		}
+		nodes[i].Data = node
+	}
+
+	doc := map[string][]*resultNode{atomicResultsMember: nodes}
+	return json.NewEncoder(w).Encode(doc)
+}
@@ -0,0 +1,185 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalOperations(t *testing.T) {
+	in := strings.NewReader(`{"atomic:operations": [
+		{"op": "add", "data": {"type": "users", "lid": "ann", "attributes": {"name": "Ann"}}},
+		{"op": "add", "data": {"type": "activities", "relationships": {
+			"actor": {"data": {"type": "users", "lid": "ann"}},
+			"participants": {"data": [{"type": "bots", "id": "2"}, {"type": "users", "lid": "ann"}]}
+		}}},
+		{"op": "update", "ref": {"type": "users", "lid": "ann", "relationship": "friends"},
+			"data": [{"type": "users", "lid": "ann"}]},
+		{"op": "remove", "ref": {"type": "users", "lid": "ann"}}
+	]}`)
+
+	ops, err := UnmarshalOperations(in, UnmarshalTypes(testActorTypes(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 4, len(ops.Operations); e != a {
+		t.Fatalf("Was expecting %d operations, got %d", e, a)
+	}
+
+	model, err := ops.Operations[0].Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := (&User{Name: "Ann"}), model; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting %#v, got %#v", e, a)
+	}
+	ops.Operations[0].Resolve("1")
+
+	model, err = ops.Operations[1].Model()
+	if err != nil {
+		t.Fatal(err)
+	}
+	activity := &Activity{
+		Actor:        &User{ID: 1},
+		Participants: []Actor{&Bot{ID: 2}, &User{ID: 1}},
+	}
+	if !reflect.DeepEqual(activity, model) {
+		t.Fatalf("Was expecting %#v, got %#v", activity, model)
+	}
+
+	update := ops.Operations[2]
+	if !update.IsRelationship() {
+		t.Fatal("Was expecting the update to target a relationship")
+	}
+	if e, a := "1", update.Ref.ID; e != a {
+		t.Fatalf("Was expecting the ref to be resolved to %q, got %q", e, a)
+	}
+	linkage, err := update.Linkage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !linkage.IsMany || len(linkage.Data) != 1 || linkage.Data[0].ID != "1" {
+		t.Fatalf("Was expecting the linkage to be resolved, got %#v", linkage)
+	}
+
+	if e, a := "1", ops.Operations[3].Ref.ID; e != a {
+		t.Fatalf("Was expecting the ref to be resolved to %q, got %q", e, a)
+	}
+}
+
+func TestUnmarshalOperations_invalid(t *testing.T) {
+	in := strings.NewReader(`{"atomic:operations": [
+		{"op": "add", "data": {"type": "users"}},
+		{"op": "add"},
+		{"op": "replace", "ref": {"type": "users", "id": "1"}},
+		{"op": "remove", "ref": {"type": "users"}, "href": "/users/1"},
+		{"op": "remove"},
+		{"op": "add", "data": null},
+		{"op": "update", "ref": {"type": "users", "id": "1"}, "data": null},
+		{"op": "update", "ref": {"type": "users", "id": "1", "relationship": "friend"}, "data": null}
+	]}`)
+
+	_, err := UnmarshalOperations(in)
+	errs, ok := err.(ErrorObjects)
+	if !ok {
+		t.Fatalf("Was expecting ErrorObjects, got %v", err)
+	}
+
+	var pointers []string
+	for _, e := range errs {
+		if e.Status != "400" {
+			t.Fatalf("Was expecting a 400 status, got %q", e.Status)
+		}
+		pointers = append(pointers, e.Source.Pointer)
+	}
+	expected := []string{
+		"/atomic:operations/1/data",
+		"/atomic:operations/2/op",
+		"/atomic:operations/3",
+		"/atomic:operations/3/ref",
+		"/atomic:operations/4",
+		"/atomic:operations/5/data",
+		"/atomic:operations/6/data",
+	}
+	if !reflect.DeepEqual(expected, pointers) {
+		t.Fatalf("Was expecting errors at %v, got %v", expected, pointers)
+	}
+}
+
+func TestUnmarshalOperations_missingOperations(t *testing.T) {
+	_, err := UnmarshalOperations(strings.NewReader(`{"data": []}`))
+	errs, ok := err.(ErrorObjects)
+	if !ok || len(errs) != 1 || errs[0].Source.Pointer != "/atomic:operations" {
+		t.Fatalf("Was expecting an error at /atomic:operations, got %v", err)
+	}
+}
+
+func TestOperation_Model_errors(t *testing.T) {
+	in := strings.NewReader(`{"atomic:operations": [
+		{"op": "add", "data": {"type": "users", "attributes": {"name": 1}}},
+		{"op": "add", "data": {"type": "activities", "relationships": {
+			"actor": {"data": {"type": "users", "lid": "unknown"}}
+		}}},
+		{"op": "add", "data": {"type": "blogs"}}
+	]}`)
+
+	ops, err := UnmarshalOperations(in, UnmarshalTypes(testActorTypes(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"/atomic:operations/0/data/attributes/name",
+		"/atomic:operations/1/data/relationships/actor/data/lid",
+		"/atomic:operations/2/data/type",
+	}
+	for i, op := range ops.Operations {
+		_, err := op.Model()
+		uerr, ok := err.(*UnmarshalError)
+		if !ok {
+			t.Fatalf("Was expecting an *UnmarshalError for operation %d, got %v", i, err)
+		}
+		if e, a := expected[i], uerr.ErrorObject().Source.Pointer; e != a {
+			t.Fatalf("Was expecting the error of operation %d at %q, got %q", i, e, a)
+		}
+	}
+}
+
+func TestMarshalResults(t *testing.T) {
+	results := []*OperationResult{
+		{Data: &User{ID: 1, Name: "Ann"}},
+		nil,
+		{Meta: &Meta{"deleted": true}},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalResults(buf, results); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := json.Marshal(map[string]interface{}{
+		"atomic:results": []interface{}{
+			map[string]interface{}{
+				"data": map[string]interface{}{
+					"type":       "users",
+					"id":         "1",
+					"attributes": map[string]interface{}{"name": "Ann"},
+				},
+			},
+			map[string]interface{}{},
+			map[string]interface{}{"meta": map[string]interface{}{"deleted": true}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := isJSONEqual(expected, buf.Bytes()); err != nil || !ok {
+		t.Fatalf("Was expecting %s, got %s", expected, buf.Bytes())
+	}
+
+	if err := MarshalResults(buf, []*OperationResult{{Data: User{}}}); err != ErrUnexpectedType {
+		t.Fatalf("Was expecting ErrUnexpectedType, got %v", err)
+	}
+}
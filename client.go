@@ -0,0 +1,293 @@
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Client is a client of a JSON API server:
+//
+//	c := jsonapi.NewClient("https://example.com/api")
+//
+//	blog := new(Blog)
+//	err := c.Get(ctx, "/blogs/1", blog, &jsonapi.Query{Include: []string{"posts"}})
+//
+//	var blogs []*Blog
+//	err = c.List(ctx, "/blogs", &blogs, &jsonapi.Query{
+//		Sort: []jsonapi.SortField{{Field: "created_at", Descending: true}},
+//	})
+//
+// Paths are relative to the base URL; absolute URLs, such as links given by
+// the server, are requested as they are. An error response is returned as
+// ErrorObjects, with the error objects of the errors document, or, if the
+// response has none, a single error object with the status of the response.
+type Client struct {
+	// BaseURL is the URL that request paths are relative to.
+	BaseURL string
+	// HTTPClient sends the requests; it is http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// Header holds headers to add to every request, such as Authorization.
+	Header http.Header
+	// UnmarshalOptions apply to the documents of every response.
+	UnmarshalOptions []UnmarshalOption
+}
+
+// NewClient returns a Client for the server at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: baseURL, Header: http.Header{}}
+}
+
+// Get fetches a single resource into model, a struct pointer. The query
+// parameters of q, which may be nil, are added to the request.
+func (c *Client) Get(ctx context.Context, path string, model interface{}, q *Query) error {
+	res, err := c.do(ctx, http.MethodGet, path, q, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return UnmarshalPayload(res.Body, model, c.UnmarshalOptions...)
+}
+
+// List fetches a collection of resources into models, a pointer to a slice of
+// struct pointers, e.g. *[]*Blog, or of an interface implemented by registered
+// types. See Iterate to fetch every page of a paginated collection.
+func (c *Client) List(ctx context.Context, path string, models interface{}, q *Query) error {
+	slice := reflect.ValueOf(models)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice ||
+		!isModelType(slice.Elem().Type().Elem()) {
+		return ErrUnexpectedType
+	}
+	slice = slice.Elem()
+
+	items := reflect.MakeSlice(slice.Type(), 0, 0)
+	if _, err := c.page(ctx, path, q, slice.Type().Elem(), func(model reflect.Value) {
+		items = reflect.Append(items, model)
+	}); err != nil {
+		return err
+	}
+	slice.Set(items)
+	return nil
+}
+
+// isModelType reports whether t is a type of model that a collection can be
+// unmarshaled into: a struct pointer, or an interface.
+func isModelType(t reflect.Type) bool {
+	return t != nil && (t.Kind() == reflect.Interface ||
+		t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct)
+}
+
+// clientPage holds the top-level members of a page of a collection.
+type clientPage struct {
+	// url is the URL the page was fetched from.
+	url   *url.URL
+	links *Links
+	meta  *Meta
+}
+
+// page fetches the collection at path, calling add with each of its models,
+// as unmarshalMany does.
+func (c *Client) page(ctx context.Context, path string, q *Query, t reflect.Type, add func(model reflect.Value)) (*clientPage, error) {
+	res, err := c.do(ctx, http.MethodGet, path, q, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshalMany(bytes.NewReader(body), t, newUnmarshalOptions(c.UnmarshalOptions), add); err != nil {
+		return nil, err
+	}
+
+	top := struct {
+		Links *Links `json:"links"`
+		Meta  *Meta  `json:"meta"`
+	}{}
+	if err := json.Unmarshal(body, &top); err != nil {
+		return nil, err
+	}
+	return &clientPage{url: res.Request.URL, links: top.Links, meta: top.Meta}, nil
+}
+
+// Create creates the resource model, a struct pointer, by posting it to path,
+// and unmarshals the created resource of the response into model. A zero
+// primary field is left out of the request, for the server to assign the id.
+// The MarshalOptions apply to the request document.
+func (c *Client) Create(ctx context.Context, path string, model interface{}, q *Query, opts ...MarshalOption) error {
+	return c.send(ctx, http.MethodPost, path, model, q, opts)
+}
+
+// Update updates the resource model, a struct pointer, at path, and unmarshals
+// the updated resource of the response, if any, into model. The
+// MarshalOptions apply to the request document; use MarshalFields to send
+// only the members to update:
+//
+//	c.Update(ctx, "/blogs/1", blog, nil, jsonapi.MarshalFields(map[string][]string{
+//		"blogs": {"title"},
+//	}))
+func (c *Client) Update(ctx context.Context, path string, model interface{}, q *Query, opts ...MarshalOption) error {
+	return c.send(ctx, http.MethodPatch, path, model, q, opts)
+}
+
+// Delete deletes the resource at path.
+func (c *Client) Delete(ctx context.Context, path string) error {
+	res, err := c.do(ctx, http.MethodDelete, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// the body, such as a meta document, is read so the connection can be
+	// reused
+	_, err = io.Copy(ioutil.Discard, res.Body)
+	return err
+}
+
+// send sends model with method, and unmarshals the response into it. A
+// response without primary data, such as 204 No Content or a document with
+// only meta, leaves model as it is.
+func (c *Client) send(ctx context.Context, method, path string, model interface{}, q *Query, opts []MarshalOption) error {
+	vals := reflect.ValueOf(model)
+	if vals.Kind() != reflect.Ptr || reflect.Indirect(vals).Kind() != reflect.Struct {
+		return ErrUnexpectedType
+	}
+
+	payload, err := marshalOne(model, newMarshalOptions(opts))
+	if err != nil {
+		return err
+	}
+	if method == http.MethodPost && hasZeroID(vals.Elem()) {
+		payload.Data.ID = ""
+	}
+	// related resources are not sent, only their linkage
+	payload.clearIncluded()
+	body := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(body).Encode(payload); err != nil {
+		return err
+	}
+
+	res, err := c.do(ctx, method, path, q, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	doc, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if !hasPrimaryData(doc) {
+		return nil
+	}
+	return UnmarshalPayload(bytes.NewReader(doc), model, c.UnmarshalOptions...)
+}
+
+// hasPrimaryData reports whether the response document doc, which may be
+// empty, has a data member. A document that does not decode is taken to have
+// one, for UnmarshalPayload to report the error.
+func hasPrimaryData(doc []byte) bool {
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return false
+	}
+	top := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	return json.Unmarshal(doc, &top) != nil || top.Data != nil
+}
+
+// do sends a request, and returns the response if it is successful; an error
+// response is returned as ErrorObjects.
+func (c *Client) do(ctx context.Context, method, path string, q *Query, body io.Reader) (*http.Response, error) {
+	u, err := c.url(path, q)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range c.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set(headerAccept, MediaType)
+	if body != nil {
+		req.Header.Set(headerContentType, MediaType)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		return nil, responseErrors(res)
+	}
+	return res, nil
+}
+
+// url returns the URL of path with the query parameters of q.
+func (c *Client) url(path string, q *Query) (string, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() {
+		u, err = url.Parse(strings.TrimSuffix(c.BaseURL, "/") + "/" + strings.TrimPrefix(path, "/"))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if q != nil {
+		values := u.Query()
+		for param, vals := range q.Values() {
+			values[param] = vals
+		}
+		u.RawQuery = values.Encode()
+	}
+	return u.String(), nil
+}
+
+// responseErrors returns the ErrorObjects of an error response.
+func responseErrors(res *http.Response) error {
+	if errs, err := UnmarshalErrors(res.Body); err == nil && len(errs) > 0 {
+		return ErrorObjects(errs)
+	}
+	return ErrorObjects{{
+		Title:  http.StatusText(res.StatusCode),
+		Status: strconv.Itoa(res.StatusCode),
+	}}
+}
+
+// hasZeroID reports whether the primary field of the struct value v is zero.
+func hasZeroID(v reflect.Value) bool {
+	for _, f := range schemaOf(v.Type()).fields {
+		if f.annotation == annotationPrimary {
+			return isZeroValue(v.Field(f.index))
+		}
+	}
+	return false
+}
+
+// isZeroValue reports whether v is the zero value of its type.
+func isZeroValue(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
@@ -0,0 +1,164 @@
+package jsonapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func testClient(t *testing.T) (*Client, *blogStore, func()) {
+	srv, store := testServer(t)
+	ts := httptest.NewServer(http.StripPrefix("/api", srv))
+	return NewClient(ts.URL + "/api/"), store, ts.Close
+}
+
+func TestClient_Get(t *testing.T) {
+	c, _, done := testClient(t)
+	defer done()
+
+	blog := new(Blog)
+	if err := c.Get(context.Background(), "/blogs/1", blog, &Query{Include: []string{"posts"}}); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "Title 1", blog.Title; e != a {
+		t.Fatalf("Was expecting the title %q, got %q", e, a)
+	}
+	if len(blog.Posts) != 2 || blog.Posts[1].Title != "Post 2" {
+		t.Fatalf("Was expecting the included posts, got %#v", blog.Posts)
+	}
+}
+
+func TestClient_List(t *testing.T) {
+	c, _, done := testClient(t)
+	defer done()
+
+	var blogs []*Blog
+	if err := c.List(context.Background(), "blogs", &blogs, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(blogs) != 2 || blogs[0].ID != 1 || blogs[1].ID != 2 {
+		t.Fatalf("Was expecting blogs 1 and 2, got %#v", blogs)
+	}
+
+	if err := c.List(context.Background(), "blogs", blogs, nil); err != ErrUnexpectedType {
+		t.Fatalf("Was expecting ErrUnexpectedType, got %v", err)
+	}
+	if err := c.List(context.Background(), "blogs", &[]Blog{}, nil); err != ErrUnexpectedType {
+		t.Fatalf("Was expecting ErrUnexpectedType, got %v", err)
+	}
+}
+
+func TestClient_Create(t *testing.T) {
+	c, store, done := testClient(t)
+	defer done()
+
+	blog := &Blog{Title: "New"}
+	if err := c.Create(context.Background(), "/blogs", blog, nil); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 3, blog.ID; e != a {
+		t.Fatalf("Was expecting the id %d, got %d", e, a)
+	}
+	if e, a := "New", store.blogs["3"].Title; e != a {
+		t.Fatalf("Was expecting the title %q, got %q", e, a)
+	}
+}
+
+func TestClient_Update(t *testing.T) {
+	c, store, done := testClient(t)
+	defer done()
+
+	blog := &Blog{ID: 1, Title: "Updated"}
+	if err := c.Update(context.Background(), "/blogs/1", blog, nil, MarshalFields(map[string][]string{
+		"blogs": {"title"},
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 2, len(store.blogs["1"].Posts); e != a {
+		t.Fatalf("Was expecting the posts to be kept, got %d", a)
+	}
+	if e, a := 2, len(blog.Posts); e != a {
+		t.Fatalf("Was expecting the response to be unmarshaled, got %d posts", a)
+	}
+}
+
+func TestClient_Delete(t *testing.T) {
+	c, store, done := testClient(t)
+	defer done()
+
+	if err := c.Delete(context.Background(), "/blogs/2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.blogs["2"]; ok {
+		t.Fatal("Was expecting the blog to be deleted")
+	}
+
+	err := c.Delete(context.Background(), "/blogs/2")
+	errs, ok := err.(ErrorObjects)
+	if !ok || errs.StatusCode() != http.StatusNotFound {
+		t.Fatalf("Was expecting a 404 error, got %v", err)
+	}
+}
+
+func TestClient_noPrimaryData(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil && err != io.EOF {
+			t.Error(err)
+		}
+		if _, ok := doc["included"]; ok {
+			t.Errorf("Was expecting no included resources in the request, got %v", doc["included"])
+		}
+
+		w.Header().Set(headerContentType, MediaType)
+		switch r.Method {
+		case http.MethodPost:
+			// an empty body
+		case http.MethodPatch, http.MethodDelete:
+			w.Write([]byte(`{"meta": {"updated": true}}`))
+		}
+	}))
+	defer ts.Close()
+	c := NewClient(ts.URL)
+
+	blog := testBlog()
+	expected := *blog
+	if err := c.Create(context.Background(), "/blogs", blog, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Update(context.Background(), "/blogs/5", blog, nil); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := expected, *blog; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the model to be kept, got %#v", a)
+	}
+	if err := c.Delete(context.Background(), "/blogs/5"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_errorResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e, a := "Bearer token", r.Header.Get("Authorization"); e != a {
+			t.Errorf("Was expecting the Authorization %q, got %q", e, a)
+		}
+		if e, a := MediaType, r.Header.Get(headerAccept); e != a {
+			t.Errorf("Was expecting the Accept %q, got %q", e, a)
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL)
+	c.Header.Set("Authorization", "Bearer token")
+
+	err := c.Get(context.Background(), "/blogs/1", new(Blog), nil)
+	errs, ok := err.(ErrorObjects)
+	if !ok || errs.StatusCode() != http.StatusServiceUnavailable {
+		t.Fatalf("Was expecting a 503 error, got %v", err)
+	}
+}
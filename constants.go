@@ -5,6 +5,7 @@ const (
 	annotationJSONAPI   = "jsonapi"
 	annotationPrimary   = "primary"
 	annotationClientID  = "client-id"
+	annotationLid       = "lid"
 	annotationAttribute = "attr"
 	annotationRelation  = "relation"
 	annotationOmitEmpty = "omitempty"
@@ -19,6 +20,12 @@ const (
 	// see http://jsonapi.org/format/#document-structure
 	MediaType = "application/vnd.api+json"
 
+	// KeySelfLink is the key to the links object whose value contains a link to
+	// the resource or document itself
+	//
+	// http://jsonapi.org/format/#document-links
+	KeySelfLink = "self"
+
 	// Pagination Constants
 	//
 	// http://jsonapi.org/format/#fetching-pagination
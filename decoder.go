@@ -0,0 +1,261 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Decoder reads a jsonapi document with many resources, such as a bulk
+// upload, and unmarshals the resources of its "data" array one at a time, so
+// that they can be processed as they are read:
+//
+//	dec := jsonapi.NewDecoder(r.Body)
+//	err := dec.DecodeMany(reflect.TypeOf(new(Blog)), func(model interface{}) error {
+//		return db.Insert(model.(*Blog))
+//	})
+//
+// Relationships are unmarshaled from the resources of the "included" array
+// read so far; see ResolveIncluded for documents that give "included" after
+// "data".
+type Decoder struct {
+	dec     *json.Decoder
+	opts    *unmarshalOptions
+	resolve func(index int, relationship string, model interface{}) error
+}
+
+// NewDecoder returns a Decoder that reads from r; the UnmarshalOptions apply
+// as they do to UnmarshalManyPayload, so that errors are only returned as
+// *UnmarshalErrors given UnmarshalErrorPointers.
+func NewDecoder(r io.Reader, opts ...UnmarshalOption) *Decoder {
+	return &Decoder{
+		dec:  json.NewDecoder(r),
+		opts: newUnmarshalOptions(opts),
+	}
+}
+
+// ResolveIncluded resolves the relationships of resources that are read
+// before the "included" array. Such resources are still given to the
+// DecodeMany callback as they are read, with their related resources as
+// stubs holding only the primary field. Once "included" has been read, fn is
+// called, in the order of "data", with the index in "data" of each of those
+// resources, the name of the relationship and the related model unmarshaled
+// from "included":
+//
+//	dec.ResolveIncluded(func(index int, relationship string, model interface{}) error {
+//		return db.Link(ids[index], relationship, model)
+//	})
+//
+// Only the type and id of the pending linkage are held until then, not the
+// resources themselves. Linkage to resources that are not in "included" is
+// left as it is.
+func (d *Decoder) ResolveIncluded(fn func(index int, relationship string, model interface{}) error) {
+	d.resolve = fn
+}
+
+// pendingLinkage is the linkage of a resource of "data" to a resource that
+// was not in "included" when it was read.
+type pendingLinkage struct {
+	index        int
+	relationship string
+	key          string
+	typ          reflect.Type
+}
+
+// DecodeMany reads the document and calls fn with each resource of its "data"
+// array, in order, unmarshaled into a new instance of t, a pointer to a struct
+// type; as with UnmarshalManyPayload, t may be an interface type given
+// UnmarshalTypes. Reading stops at the first error, which is returned; this
+// includes an error returned by fn, and the ErrorObjects of an errors payload.
+//
+// A document does not have both "data" and "errors", but if "errors" follows
+// "data", fn has already been called with the resources of "data" by the time
+// the ErrorObjects are returned.
+func (d *Decoder) DecodeMany(t reflect.Type, fn func(model interface{}) error) error {
+	included := map[string]*Node{}
+	var includedRead bool
+	var pending []pendingLinkage
+
+	if err := d.expectDelim('{', ""); err != nil {
+		return err
+	}
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		member, _ := tok.(string)
+
+		switch member {
+		case "data":
+			err = d.decodeArray("/data", func(i int, n *Node) error {
+				model, err := d.opts.newModel(t, n)
+				if err != nil {
+					return d.opts.unmarshalError(err)
+				}
+				if d.resolve != nil && !includedRead {
+					pending = append(pending, pendingLinkages(i, n, model.Type().Elem())...)
+				}
+				if err := unmarshalResource(n, model, &included, d.opts); err != nil {
+					return d.opts.unmarshalError(err)
+				}
+				return fn(model.Interface())
+			})
+		case "included":
+			err = d.decodeArray("/included", func(i int, n *Node) error {
+				included[nodeKey(n)] = n
+				return nil
+			})
+			if err == nil {
+				includedRead = true
+				err = d.resolvePending(pending, included)
+				pending = nil
+			}
+		case "errors":
+			var errs ErrorObjects
+			if err = d.dec.Decode(&errs); err == nil && len(errs) > 0 {
+				return errs
+			}
+		default:
+			var skipped json.RawMessage
+			err = d.dec.Decode(&skipped)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return d.expectDelim('}', "")
+}
+
+// resolvePending calls the ResolveIncluded callback with the related model of
+// each pending linkage that is in included.
+func (d *Decoder) resolvePending(pending []pendingLinkage, included map[string]*Node) error {
+	for _, p := range pending {
+		n, ok := included[p.key]
+		if !ok {
+			continue
+		}
+		model, err := d.opts.newModel(p.typ, n)
+		if err != nil {
+			return d.opts.unmarshalError(err)
+		}
+		if err := unmarshalResource(deepCopyNode(n), model, &included, d.opts); err != nil {
+			return d.opts.unmarshalError(err)
+		}
+		if err := d.resolve(p.index, p.relationship, model.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pendingLinkages returns the linkage of the resource n, the index-th of
+// "data", for the relationships declared by the struct type t.
+func pendingLinkages(index int, n *Node, t reflect.Type) []pendingLinkage {
+	schema := schemaOf(t)
+
+	var pending []pendingLinkage
+	for _, name := range sortedKeys(n.Relationships) {
+		relType, ok := schema.relTypes[name]
+		if !ok || relType == nil {
+			continue
+		}
+		if relType.Kind() == reflect.Struct {
+			relType = reflect.PtrTo(relType)
+		}
+
+		raw, err := rawValue(n.Relationships[name])
+		if err != nil {
+			continue
+		}
+		relationship := struct {
+			Data json.RawMessage `json:"data"`
+		}{}
+		if err := json.Unmarshal(raw, &relationship); err != nil {
+			continue
+		}
+		var idents []*resourceIdentifier
+		if err := json.Unmarshal(relationship.Data, &idents); err != nil {
+			ident := new(resourceIdentifier)
+			if err := json.Unmarshal(relationship.Data, ident); err != nil {
+				continue
+			}
+			idents = []*resourceIdentifier{ident}
+		}
+
+		for _, ident := range idents {
+			if ident == nil || ident.Type == "" {
+				continue
+			}
+			key := nodeKey(&Node{Type: ident.Type, ID: ident.ID, Lid: ident.Lid})
+			pending = append(pending, pendingLinkage{index: index, relationship: name, key: key, typ: relType})
+		}
+	}
+	return pending
+}
+
+// decodeArray decodes the nodes of the array at pointer, calling fn with the
+// index of each of them as it is read. A null array has no nodes.
+func (d *Decoder) decodeArray(pointer string, fn func(int, *Node) error) error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return d.opts.unmarshalError(&UnmarshalError{
+			Pointer:  pointer,
+			Expected: "array",
+			Actual:   tokenJSONType(tok),
+			Err:      errors.New("expected an array of resources"),
+		})
+	}
+
+	for i := 0; d.dec.More(); i++ {
+		r := new(rawNode)
+		if err := d.dec.Decode(r); err != nil {
+			return err
+		}
+		n := r.node()
+		if n == nil {
+			continue
+		}
+		n.pointer = fmt.Sprintf("%s/%d", pointer, i)
+		if err := fn(i, n); err != nil {
+			return err
+		}
+	}
+
+	return d.expectDelim(']', pointer)
+}
+
+// expectDelim reads the next token, which must be the delimiter delim.
+func (d *Decoder) expectDelim(delim json.Delim, pointer string) error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return d.opts.unmarshalError(&UnmarshalError{
+			Pointer: pointer,
+			Err:     fmt.Errorf("expected %q, got %v", delim, tok),
+		})
+	}
+	return nil
+}
+
+// tokenJSONType returns the JSON type of the value starting with tok.
+func tokenJSONType(tok json.Token) string {
+	switch tok {
+	case json.Delim('{'):
+		return "object"
+	case json.Delim('['):
+		return "array"
+	}
+	return jsonType(tok)
+}
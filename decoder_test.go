@@ -0,0 +1,193 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecoder_DecodeMany(t *testing.T) {
+	blog, other := testBlog(), testBlog()
+	other.ID = 6
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, []interface{}{blog, other}); err != nil {
+		t.Fatal(err)
+	}
+	in := buf.Bytes()
+
+	expected, err := UnmarshalManyPayload(bytes.NewReader(in), reflect.TypeOf(new(Blog)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "included" follows "data" in a marshaled payload, so the related
+	// resources are stubs
+	var blogs []interface{}
+	if err := NewDecoder(bytes.NewReader(in)).DecodeMany(reflect.TypeOf(new(Blog)), func(model interface{}) error {
+		blogs = append(blogs, model)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blogs) != 2 || blogs[0].(*Blog).ID != 5 || blogs[1].(*Blog).ID != 6 {
+		t.Fatalf("Was expecting blogs 5 and 6, got %#v", blogs)
+	}
+	if post := blogs[0].(*Blog).Posts[0]; post.ID != 1 || post.Title != "" {
+		t.Fatalf("Was expecting a stub of post 1, got %#v", post)
+	}
+	if e, a := expected[0].(*Blog).Title, blogs[0].(*Blog).Title; e != a {
+		t.Fatalf("Was expecting the title %q, got %q", e, a)
+	}
+}
+
+func TestDecoder_ResolveIncluded(t *testing.T) {
+	in := `{
+		"data": [
+			{"type": "blogs", "id": "1", "relationships": {"posts": {"data": [{"type": "posts", "id": "1"}, {"type": "posts", "id": "9"}]}}},
+			{"type": "blogs", "id": "2"},
+			{"type": "blogs", "id": "3", "relationships": {"current_post": {"data": {"type": "posts", "id": "2"}}}}
+		],
+		"included": [
+			{"type": "posts", "id": "1", "attributes": {"title": "Foo"}},
+			{"type": "posts", "id": "2", "attributes": {"title": "Bar"}}
+		]
+	}`
+
+	type resolved struct {
+		index        int
+		relationship string
+		title        string
+	}
+	var ids []int
+	var got []resolved
+
+	dec := NewDecoder(strings.NewReader(in))
+	dec.ResolveIncluded(func(index int, relationship string, model interface{}) error {
+		if len(ids) != 3 {
+			t.Fatal("Was expecting all the resources to be given before their relationships are resolved")
+		}
+		got = append(got, resolved{index, relationship, model.(*Post).Title})
+		return nil
+	})
+	if err := dec.DecodeMany(reflect.TypeOf(new(Blog)), func(model interface{}) error {
+		ids = append(ids, model.(*Blog).ID)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, a := []int{1, 2, 3}, ids; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the blogs in the order of data %v, got %v", e, a)
+	}
+	// post 9 is not included
+	expected := []resolved{{0, "posts", "Foo"}, {2, "current_post", "Bar"}}
+	if !reflect.DeepEqual(expected, got) {
+		t.Fatalf("Was expecting the resolved posts %v, got %v", expected, got)
+	}
+}
+
+func TestDecoder_DecodeMany_includedFirst(t *testing.T) {
+	in := `{
+		"included": [{"type": "posts", "id": "1", "attributes": {"title": "Foo"}}],
+		"data": [{"type": "blogs", "id": "5", "relationships": {"current_post": {"data": {"type": "posts", "id": "1"}}}}]
+	}`
+
+	var blog *Blog
+	if err := NewDecoder(strings.NewReader(in)).DecodeMany(reflect.TypeOf(new(Blog)), func(model interface{}) error {
+		blog = model.(*Blog)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if blog.CurrentPost == nil || blog.CurrentPost.Title != "Foo" {
+		t.Fatalf("Was expecting the included post, got %#v", blog.CurrentPost)
+	}
+}
+
+func TestDecoder_DecodeMany_errors(t *testing.T) {
+	stop := errors.New("stop")
+
+	scenarios := []struct {
+		name     string
+		in       string
+		opts     []UnmarshalOption
+		fn       func(interface{}) error
+		validate func(error) bool
+	}{
+		{
+			name: "callback error",
+			in:   `{"data": [{"type": "blogs", "id": "1"}, {"type": "blogs", "id": "2"}]}`,
+			fn:   func(interface{}) error { return stop },
+			validate: func(err error) bool {
+				return err == stop
+			},
+		},
+		{
+			name: "errors payload",
+			in:   `{"errors": [{"title": "Bad", "status": "400"}]}`,
+			validate: func(err error) bool {
+				errs, ok := err.(ErrorObjects)
+				return ok && errs[0].Title == "Bad"
+			},
+		},
+		{
+			name: "errors after data",
+			in:   `{"data": [{"type": "blogs", "id": "1"}], "errors": [{"title": "Bad", "status": "400"}]}`,
+			validate: func(err error) bool {
+				errs, ok := err.(ErrorObjects)
+				return ok && errs[0].Title == "Bad"
+			},
+		},
+		{
+			name: "data not an array",
+			in:   `{"data": {"type": "blogs", "id": "1"}}`,
+			opts: []UnmarshalOption{UnmarshalErrorPointers()},
+			validate: func(err error) bool {
+				uErr, ok := err.(*UnmarshalError)
+				return ok && uErr.Pointer == "/data" && uErr.Actual == "object"
+			},
+		},
+		{
+			name: "invalid attribute",
+			in:   `{"data": [{"type": "blogs", "id": "1"}, {"type": "blogs", "id": "2", "attributes": {"title": 5}}]}`,
+			opts: []UnmarshalOption{UnmarshalErrorPointers()},
+			validate: func(err error) bool {
+				uErr, ok := err.(*UnmarshalError)
+				return ok && uErr.Pointer == "/data/1/attributes/title"
+			},
+		},
+		{
+			name: "invalid attribute without pointers",
+			in:   `{"data": [{"type": "blogs", "id": "1"}, {"type": "blogs", "id": "2", "attributes": {"title": 5}}]}`,
+			validate: func(err error) bool {
+				_, ok := err.(*json.UnmarshalTypeError)
+				return ok
+			},
+		},
+		{
+			name: "invalid id without pointers",
+			in:   `{"data": [{"type": "blogs", "id": "a"}]}`,
+			validate: func(err error) bool {
+				return err == ErrBadJSONAPIID
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			fn := scenario.fn
+			if fn == nil {
+				fn = func(interface{}) error { return nil }
+			}
+			err := NewDecoder(strings.NewReader(scenario.in), scenario.opts...).DecodeMany(reflect.TypeOf(new(Blog)), fn)
+			if !scenario.validate(err) {
+				t.Fatalf("Unexpected error: %#v", err)
+			}
+		})
+	}
+}
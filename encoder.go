@@ -0,0 +1,229 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// ErrEncoderState is returned when the methods of an Encoder are not called in
+// the order Begin, WriteResource, End.
+var ErrEncoderState = errors.New("Encoder methods must be called in the order Begin, WriteResource, End")
+
+type encoderState int
+
+const (
+	encoderNew encoderState = iota
+	encoderBegun
+	encoderEnded
+)
+
+// Encoder writes a collection of resources as a jsonapi document one resource
+// at a time, so that very large collections need not be held in memory:
+//
+//	enc := jsonapi.NewEncoder(w, jsonapi.MarshalInclude("posts"))
+//	if err := enc.Begin(); err != nil {
+//		return err
+//	}
+//	for blog := range blogs { // e.g. a channel fed by a database cursor
+//		if err := enc.WriteResource(blog); err != nil {
+//			return err
+//		}
+//	}
+//	return enc.End()
+//
+// Each resource is written to the "data" array as soon as it is given. Only
+// the related resources to be sideloaded are kept, already encoded, until End
+// writes the "included" array along with the top-level "links", "meta" and
+// "jsonapi" members. The document is byte-for-byte the one MarshalPayload
+// writes for a slice of the same models and options; as there is no slice,
+// top-level links and meta are only given by MarshalLinks and MarshalMeta.
+type Encoder struct {
+	w     io.Writer
+	opts  *marshalOptions
+	state encoderState
+	err   error
+	// count is the number of resources written so far.
+	count int
+	// validated holds the model types whose include paths have been validated.
+	validated map[reflect.Type]bool
+	// includedKeys holds the nodeKey of every included resource, and
+	// included their encoded nodes, in the order they were first reached.
+	includedKeys map[string]bool
+	included     []encodedNode
+}
+
+// encodedNode is an included node, kept as its type, id and JSON encoding.
+type encodedNode struct {
+	node *Node
+	json []byte
+}
+
+// NewEncoder returns an Encoder that writes to w; the MarshalOptions apply as
+// they do to MarshalPayload.
+func NewEncoder(w io.Writer, opts ...MarshalOption) *Encoder {
+	return &Encoder{
+		w:            w,
+		opts:         newMarshalOptions(opts),
+		validated:    map[reflect.Type]bool{},
+		includedKeys: map[string]bool{},
+	}
+}
+
+// Begin writes the start of the document, up to the opening of the "data"
+// array.
+func (e *Encoder) Begin() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.state != encoderNew {
+		return ErrEncoderState
+	}
+	e.state = encoderBegun
+
+	return e.write([]byte(`{"data":[`))
+}
+
+// WriteResource writes model, a struct pointer, as the next element of the
+// "data" array, and collects its related resources to be sideloaded by End.
+func (e *Encoder) WriteResource(model interface{}) error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.state != encoderBegun {
+		return ErrEncoderState
+	}
+
+	vals := reflect.ValueOf(model)
+	if vals.Kind() != reflect.Ptr || reflect.Indirect(vals).Kind() != reflect.Struct {
+		return ErrUnexpectedType
+	}
+
+	modelType := vals.Type().Elem()
+	if !e.validated[modelType] {
+		if err := e.opts.include.validate(modelType, ""); err != nil {
+			return err
+		}
+		e.validated[modelType] = true
+	}
+
+	included := newIncludedNodes()
+	node, err := visitModelNode(model, included, true, e.opts, e.opts.include)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range included.values(false) {
+		k := nodeKey(n)
+		if e.includedKeys[k] {
+			continue
+		}
+		b, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		e.includedKeys[k] = true
+		e.included = append(e.included, encodedNode{node: toShallowNode(n), json: b})
+	}
+
+	b, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if err := e.write([]byte{','}); err != nil {
+			return err
+		}
+	}
+	e.count++
+	return e.write(b)
+}
+
+// End closes the "data" array, then writes the "included" array and the
+// top-level members to complete the document.
+func (e *Encoder) End() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.state != encoderBegun {
+		return ErrEncoderState
+	}
+	e.state = encoderEnded
+
+	if err := e.write([]byte{']'}); err != nil {
+		return err
+	}
+
+	if len(e.included) > 0 {
+		if e.opts.sortIncluded {
+			sort.Stable(encodedNodesByTypeAndID(e.included))
+		}
+		if err := e.write([]byte(`,"included":[`)); err != nil {
+			return err
+		}
+		for i, n := range e.included {
+			if i > 0 {
+				if err := e.write([]byte{','}); err != nil {
+					return err
+				}
+			}
+			if err := e.write(n.json); err != nil {
+				return err
+			}
+		}
+		if err := e.write([]byte{']'}); err != nil {
+			return err
+		}
+		e.included = nil
+	}
+
+	links, meta, err := e.opts.topLevel(nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := e.writeMember("links", links, links != nil); err != nil {
+		return err
+	}
+	if err := e.writeMember("meta", meta, meta != nil); err != nil {
+		return err
+	}
+	if err := e.writeMember("jsonapi", e.opts.jsonapi, e.opts.jsonapi != nil); err != nil {
+		return err
+	}
+
+	// json.Encoder, as used by MarshalPayload, ends the document with a newline
+	return e.write([]byte("}\n"))
+}
+
+// writeMember writes the top-level member name with the value v, if present.
+func (e *Encoder) writeMember(name string, v interface{}, present bool) error {
+	if !present {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return e.write([]byte(`,"` + name + `":` + string(b)))
+}
+
+// write writes b to the underlying writer; a write error is returned by all
+// subsequent calls.
+func (e *Encoder) write(b []byte) error {
+	if _, err := e.w.Write(b); err != nil {
+		e.err = err
+		return err
+	}
+	return nil
+}
+
+// encodedNodesByTypeAndID sorts encoded nodes as nodesByTypeAndID does.
+type encodedNodesByTypeAndID []encodedNode
+
+func (n encodedNodesByTypeAndID) Len() int      { return len(n) }
+func (n encodedNodesByTypeAndID) Swap(i, j int) { n[i], n[j] = n[j], n[i] }
+func (n encodedNodesByTypeAndID) Less(i, j int) bool {
+	return lessByTypeAndID(n[i].node, n[j].node)
+}
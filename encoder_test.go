@@ -0,0 +1,99 @@
+package jsonapi
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncoder_sameAsMarshalPayload(t *testing.T) {
+	blog, other := testBlog(), testBlog()
+	other.ID = 6
+	blogs := []interface{}{blog, other}
+
+	scenarios := []struct {
+		name   string
+		models []interface{}
+		opts   []MarshalOption
+	}{
+		{name: "no models", models: []interface{}{}},
+		{name: "defaults", models: blogs},
+		{name: "include", models: blogs, opts: []MarshalOption{MarshalInclude("posts")}},
+		{name: "sort included", models: blogs, opts: []MarshalOption{MarshalSortIncluded()}},
+		{name: "fields", models: blogs, opts: []MarshalOption{MarshalFields(map[string][]string{"blogs": {"title"}})}},
+		{
+			name:   "top-level members",
+			models: blogs,
+			opts: []MarshalOption{
+				MarshalLinks(Links{"self": "http://example.com/blogs"}),
+				MarshalMeta(Meta{"total": 2}),
+				MarshalJSONAPI(JSONAPIObject{Version: "1.0"}),
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			expected := bytes.NewBuffer(nil)
+			if err := MarshalPayload(expected, scenario.models, scenario.opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			out := bytes.NewBuffer(nil)
+			enc := NewEncoder(out, scenario.opts...)
+			if err := enc.Begin(); err != nil {
+				t.Fatal(err)
+			}
+			for _, model := range scenario.models {
+				if err := enc.WriteResource(model); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := enc.End(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(expected.Bytes(), out.Bytes()) {
+				t.Fatalf("Was expecting\n%s\ngot\n%s", expected.Bytes(), out.Bytes())
+			}
+		})
+	}
+}
+
+func TestEncoder_order(t *testing.T) {
+	enc := NewEncoder(bytes.NewBuffer(nil))
+
+	if err := enc.WriteResource(testBlog()); err != ErrEncoderState {
+		t.Fatalf("Was expecting ErrEncoderState before Begin, got %v", err)
+	}
+	if err := enc.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Begin(); err != ErrEncoderState {
+		t.Fatalf("Was expecting ErrEncoderState on a second Begin, got %v", err)
+	}
+	if err := enc.WriteResource(Blog{}); err != ErrUnexpectedType {
+		t.Fatalf("Was expecting ErrUnexpectedType for a struct, got %v", err)
+	}
+	if err := enc.End(); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.WriteResource(testBlog()); err != ErrEncoderState {
+		t.Fatalf("Was expecting ErrEncoderState after End, got %v", err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestEncoder_writeError(t *testing.T) {
+	enc := NewEncoder(failingWriter{})
+
+	if err := enc.Begin(); err == nil {
+		t.Fatal("Was expecting the write error")
+	}
+	if err := enc.End(); err == nil || err == ErrEncoderState {
+		t.Fatalf("Was expecting the write error to be returned again, got %v", err)
+	}
+}
@@ -2,10 +2,17 @@ package jsonapi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 )
 
+// ErrInvalidErrorsPayload is returned by UnmarshalErrors when the document does
+// not have an "errors" member, i.e. it is not an errors payload.
+var ErrInvalidErrorsPayload = errors.New("the document does not have an errors member")
+
 // MarshalErrors writes a JSON API response using the given `[]error`.
 //
 // For more information on JSON API error payloads, see the spec here:
@@ -18,6 +25,22 @@ func MarshalErrors(w io.Writer, errorObjects []*ErrorObject) error {
 	return nil
 }
 
+// UnmarshalErrors reads a JSON API errors payload, such as one written by
+// MarshalErrors, and returns its error objects. A document without an
+// "errors" member results in ErrInvalidErrorsPayload.
+func UnmarshalErrors(in io.Reader) ([]*ErrorObject, error) {
+	payload := struct {
+		Errors *[]*ErrorObject `json:"errors"`
+	}{}
+	if err := json.NewDecoder(in).Decode(&payload); err != nil {
+		return nil, err
+	}
+	if payload.Errors == nil {
+		return nil, ErrInvalidErrorsPayload
+	}
+	return *payload.Errors, nil
+}
+
 // ErrorsPayload is a serializer struct for representing a valid JSON API errors payload.
 type ErrorsPayload struct {
 	Errors []*ErrorObject `json:"errors"`
@@ -45,11 +68,117 @@ type ErrorObject struct {
 	// Code is an application-specific error code, expressed as a string value.
 	Code string `json:"code,omitempty"`
 
+	// Source is an object containing references to the source of the error.
+	Source *ErrorSource `json:"source,omitempty"`
+
+	// Links is a links object that may contain an "about" link leading to further details about this particular occurrence of the problem.
+	Links *Links `json:"links,omitempty"`
+
 	// Meta is an object containing non-standard meta-information about the error.
 	Meta *map[string]interface{} `json:"meta,omitempty"`
 }
 
+// ErrorSource is an implementation of the JSON API error object's source member.
+type ErrorSource struct {
+	// Pointer is a JSON Pointer [RFC6901] to the associated entity in the request document, e.g. "/data/attributes/title".
+	Pointer string `json:"pointer,omitempty"`
+
+	// Parameter is a string indicating which URI query parameter caused the error.
+	Parameter string `json:"parameter,omitempty"`
+
+	// Header is a string indicating the name of a single request header which caused the error.
+	Header string `json:"header,omitempty"`
+}
+
 // Error implements the `Error` interface.
 func (e *ErrorObject) Error() string {
 	return fmt.Sprintf("Error: %s %s\n", e.Title, e.Detail)
 }
+
+// StatusCode returns the HTTP status code of the error object, or 0 if Status
+// is not a valid code.
+func (e *ErrorObject) StatusCode() int {
+	code, err := strconv.Atoi(e.Status)
+	if err != nil || code < 100 || code > 599 {
+		return 0
+	}
+	return code
+}
+
+// ErrorObjects is an `Error` implementation holding the error objects of a
+// JSON API errors payload. The Unmarshal methods return it when the given
+// payload is an errors payload rather than a data payload.
+type ErrorObjects []*ErrorObject
+
+// Error implements the `Error` interface.
+func (e ErrorObjects) Error() string {
+	msgs := make([]string, len(e))
+	for i, obj := range e {
+		msgs[i] = strings.TrimSuffix(obj.Error(), "\n")
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// StatusCode returns the HTTP status code of the first error object, or 0 if
+// there is none.
+func (e ErrorObjects) StatusCode() int {
+	if len(e) == 0 {
+		return 0
+	}
+	return e[0].StatusCode()
+}
+
+// UnmarshalError is returned by the Unmarshal methods when a member of the
+// payload could not be unmarshaled into the model; UnmarshalPayload,
+// UnmarshalManyPayload, UnmarshalRelationship and Decoder only return it with
+// UnmarshalErrorPointers.
+//
+// Use ErrorObject to convert it to a 422 ErrorObject that points the client to
+// the invalid member of its request document.
+type UnmarshalError struct {
+	// Pointer is the JSON Pointer to the invalid member, e.g.
+	// "/data/attributes/view_count".
+	Pointer string
+	// Expected is the JSON type the model expected, e.g. "number"; Actual is
+	// the JSON type found in the payload, e.g. "string". Both are empty if the
+	// error is not a type mismatch.
+	Expected string
+	Actual   string
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the `Error` interface.
+func (e *UnmarshalError) Error() string {
+	msg := e.Err.Error()
+	if e.Expected != "" {
+		msg = fmt.Sprintf("%s (expected %s, got %s)", msg, e.Expected, e.Actual)
+	}
+	if e.Pointer != "" {
+		msg = e.Pointer + ": " + msg
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorObject converts the error to an ErrorObject with a 422 status and a
+// source pointer.
+func (e *UnmarshalError) ErrorObject() *ErrorObject {
+	obj := &ErrorObject{
+		Title:  "Invalid member",
+		Detail: e.Err.Error(),
+		Status: "422",
+		Source: &ErrorSource{Pointer: e.Pointer},
+	}
+	if e.Expected != "" {
+		obj.Meta = &map[string]interface{}{
+			"expected": e.Expected,
+			"actual":   e.Actual,
+		}
+	}
+	return obj
+}
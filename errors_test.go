@@ -3,9 +3,11 @@ package jsonapi
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -33,6 +35,13 @@ func TestMarshalErrorsWritesTheExpectedPayload(t *testing.T) {
 				map[string]interface{}{"id": "0", "title": "Test title.", "detail": "Test detail", "status": "400", "code": "E1100"},
 			}},
 		},
+		{
+			Title: "TestSourceFieldIsSerializedProperly",
+			In:    []*ErrorObject{{Title: "Test title.", Source: &ErrorSource{Pointer: "/data/attributes/title"}}},
+			Out: map[string]interface{}{"errors": []interface{}{
+				map[string]interface{}{"title": "Test title.", "source": map[string]interface{}{"pointer": "/data/attributes/title"}},
+			}},
+		},
 		{
 			Title: "TestMetaFieldIsSerializedProperly",
 			In:    []*ErrorObject{{Title: "Test title.", Detail: "Test detail", Meta: &map[string]interface{}{"key": "val"}}},
@@ -55,3 +64,101 @@ func TestMarshalErrorsWritesTheExpectedPayload(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalErrorObject(t *testing.T) {
+	err := &UnmarshalError{
+		Pointer:  "/data/attributes/view_count",
+		Expected: "number",
+		Actual:   "string",
+		Err:      errors.New("json: cannot unmarshal string into Go value of type int"),
+	}
+
+	expected := &ErrorObject{
+		Title:  "Invalid member",
+		Detail: "json: cannot unmarshal string into Go value of type int",
+		Status: "422",
+		Source: &ErrorSource{Pointer: "/data/attributes/view_count"},
+		Meta:   &map[string]interface{}{"expected": "number", "actual": "string"},
+	}
+	if actual := err.ErrorObject(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected: \n%#v \nto equal: \n%#v", actual, expected)
+	}
+
+	if e, a := "/data/attributes/view_count: json: cannot unmarshal string into Go value of type int (expected number, got string)", err.Error(); e != a {
+		t.Fatalf("Was expecting the message %q, got %q", e, a)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	errs := []*ErrorObject{
+		{Title: "Not found.", Status: "404", Source: &ErrorSource{Parameter: "id"}},
+		{Title: "Conflict.", Status: "409", Meta: &map[string]interface{}{"key": "val"}},
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	if err := MarshalErrors(buffer, errs); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := UnmarshalErrors(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(errs, out) {
+		t.Fatalf("Expected: \n%#v \nto equal: \n%#v", out, errs)
+	}
+}
+
+func TestUnmarshalErrors_notAnErrorsPayload(t *testing.T) {
+	if _, err := UnmarshalErrors(strings.NewReader(`{"data": null}`)); err != ErrInvalidErrorsPayload {
+		t.Fatalf("Was expecting ErrInvalidErrorsPayload, got %v", err)
+	}
+
+	errs, err := UnmarshalErrors(strings.NewReader(`{"errors": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs == nil || len(errs) != 0 {
+		t.Fatalf("Was expecting no error objects, got %#v", errs)
+	}
+}
+
+func TestErrorObjectsStatusCode(t *testing.T) {
+	scenarios := []struct {
+		errs     ErrorObjects
+		expected int
+	}{
+		{errs: ErrorObjects{{Status: "404"}, {Status: "409"}}, expected: 404},
+		{errs: ErrorObjects{{Status: "invalid"}}, expected: 0},
+		{errs: ErrorObjects{{Status: "999"}}, expected: 0},
+		{errs: ErrorObjects{}, expected: 0},
+	}
+	for _, scenario := range scenarios {
+		if e, a := scenario.expected, scenario.errs.StatusCode(); e != a {
+			t.Fatalf("Was expecting a status code of %d, got %d", e, a)
+		}
+	}
+}
+
+func TestUnmarshalPayload_errorsPayload(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	if err := MarshalErrors(buffer, []*ErrorObject{{Title: "Forbidden.", Status: "403"}}); err != nil {
+		t.Fatal(err)
+	}
+	in := buffer.Bytes()
+
+	err := UnmarshalPayload(bytes.NewReader(in), new(Blog))
+	errs, ok := err.(ErrorObjects)
+	if !ok {
+		t.Fatalf("Was expecting ErrorObjects, got %#v", err)
+	}
+	if e, a := 403, errs.StatusCode(); e != a {
+		t.Fatalf("Was expecting a status code of %d, got %d", e, a)
+	}
+
+	if _, err := UnmarshalManyPayload(bytes.NewReader(in), reflect.TypeOf(new(Blog))); err == nil {
+		t.Fatal("Was expecting an error")
+	} else if _, ok := err.(ErrorObjects); !ok {
+		t.Fatalf("Was expecting ErrorObjects, got %#v", err)
+	}
+}
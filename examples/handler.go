@@ -7,20 +7,15 @@ import (
 	"github.com/google/jsonapi"
 )
 
-const (
-	headerAccept      = "Accept"
-	headerContentType = "Content-Type"
-)
+const headerAccept = "Accept"
 
 // ExampleHandler is the handler we are using to demonstrate building an HTTP
 // server with the jsonapi library.
 type ExampleHandler struct{}
 
+// ServeHTTP routes the request by method; content negotiation is left to
+// jsonapi.Negotiate, see main.
 func (h *ExampleHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Header.Get(headerAccept) != jsonapi.MediaType {
-		http.Error(w, "Unsupported Media Type", http.StatusUnsupportedMediaType)
-	}
-
 	var methodHandler http.HandlerFunc
 	switch r.Method {
 	case http.MethodPost:
@@ -46,19 +41,14 @@ func (h *ExampleHandler) createBlog(w http.ResponseWriter, r *http.Request) {
 
 	blog := new(Blog)
 
-	if err := jsonapiRuntime.UnmarshalPayload(r.Body, blog); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := jsonapiRuntime.UnmarshalPayload(r.Body, blog, jsonapi.UnmarshalErrorPointers()); err != nil {
+		writeUnmarshalError(w, err)
 		return
 	}
 
 	// ...do stuff with your blog...
 
-	w.WriteHeader(http.StatusCreated)
-	w.Header().Set(headerContentType, jsonapi.MediaType)
-
-	if err := jsonapiRuntime.MarshalPayload(w, blog); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	jsonapiRuntime.WriteResource(w, http.StatusCreated, blog)
 }
 
 func (h *ExampleHandler) echoBlogs(w http.ResponseWriter, r *http.Request) {
@@ -68,11 +58,7 @@ func (h *ExampleHandler) echoBlogs(w http.ResponseWriter, r *http.Request) {
 	// but, for now
 	blogs := fixtureBlogsList()
 
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set(headerContentType, jsonapi.MediaType)
-	if err := jsonapiRuntime.MarshalPayload(w, blogs); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	jsonapiRuntime.WriteResource(w, http.StatusOK, blogs)
 }
 
 func (h *ExampleHandler) showBlog(w http.ResponseWriter, r *http.Request) {
@@ -82,7 +68,12 @@ func (h *ExampleHandler) showBlog(w http.ResponseWriter, r *http.Request) {
 
 	intID, err := strconv.Atoi(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		jsonapi.WriteErrors(w, []*jsonapi.ErrorObject{{
+			Title:  "Invalid id",
+			Detail: err.Error(),
+			Status: "400",
+			Source: &jsonapi.ErrorSource{Parameter: "id"},
+		}})
 		return
 	}
 
@@ -90,12 +81,8 @@ func (h *ExampleHandler) showBlog(w http.ResponseWriter, r *http.Request) {
 
 	// but, for now
 	blog := fixtureBlogCreate(intID)
-	w.WriteHeader(http.StatusOK)
 
-	w.Header().Set(headerContentType, jsonapi.MediaType)
-	if err := jsonapiRuntime.MarshalPayload(w, blog); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	jsonapiRuntime.WriteResource(w, http.StatusOK, blog)
 }
 
 func (h *ExampleHandler) listBlogs(w http.ResponseWriter, r *http.Request) {
@@ -105,10 +92,22 @@ func (h *ExampleHandler) listBlogs(w http.ResponseWriter, r *http.Request) {
 	// but, for now
 	blogs := fixtureBlogsList()
 
-	w.Header().Set("Content-Type", jsonapi.MediaType)
-	w.WriteHeader(http.StatusOK)
+	jsonapiRuntime.WriteResource(w, http.StatusOK, blogs)
+}
 
-	if err := jsonapiRuntime.MarshalPayload(w, blogs); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// writeUnmarshalError responds to a request document that could not be
+// unmarshaled.
+func writeUnmarshalError(w http.ResponseWriter, err error) {
+	switch e := err.(type) {
+	case *jsonapi.UnmarshalError:
+		jsonapi.WriteErrors(w, []*jsonapi.ErrorObject{e.ErrorObject()})
+	case jsonapi.ErrorObjects:
+		jsonapi.WriteErrors(w, e)
+	default:
+		jsonapi.WriteErrors(w, []*jsonapi.ErrorObject{{
+			Title:  "Invalid request document",
+			Detail: err.Error(),
+			Status: "400",
+		}})
 	}
 }
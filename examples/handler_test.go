@@ -27,6 +27,9 @@ func TestExampleHandler_post(t *testing.T) {
 	if e, a := http.StatusCreated, rr.Code; e != a {
 		t.Fatalf("Expected a status of %d, got %d", e, a)
 	}
+	if e, a := jsonapi.MediaType, rr.Header().Get("Content-Type"); e != a {
+		t.Fatalf("Expected a Content-Type of %q, got %q", e, a)
+	}
 }
 
 func TestExampleHandler_put(t *testing.T) {
@@ -84,3 +87,19 @@ func TestExampleHandler_get_list(t *testing.T) {
 		t.Fatalf("Expected a status of %d, got %d", e, a)
 	}
 }
+
+func TestExampleHandler_notAcceptable(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/blogs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set(headerAccept, jsonapi.MediaType+"; charset=utf-8")
+
+	rr := httptest.NewRecorder()
+	handler := jsonapi.Negotiate(&ExampleHandler{})
+	handler.ServeHTTP(rr, r)
+
+	if e, a := http.StatusNotAcceptable, rr.Code; e != a {
+		t.Fatalf("Expected a status of %d, got %d", e, a)
+	}
+}
@@ -0,0 +1,100 @@
+//go:build go1.18
+// +build go1.18
+
+package jsonapi
+
+import (
+	"context"
+	"io"
+	"reflect"
+)
+
+// UnmarshalOne does the same as UnmarshalPayload, but returns a new *T rather
+// than unmarshaling into a given model. T must be a struct type annotated with
+// jsonapi tags:
+//
+//	blog, err := jsonapi.UnmarshalOne[Blog](r.Body)
+func UnmarshalOne[T any](in io.Reader, opts ...UnmarshalOption) (*T, error) {
+	if err := checkModelType[T](); err != nil {
+		return nil, err
+	}
+
+	model := new(T)
+	if err := UnmarshalPayload(in, model, opts...); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// UnmarshalMany does the same as UnmarshalManyPayload, but returns the models
+// as a []*T rather than a []interface{}:
+//
+//	blogs, err := jsonapi.UnmarshalMany[Blog](r.Body)
+func UnmarshalMany[T any](in io.Reader, opts ...UnmarshalOption) ([]*T, error) {
+	if err := checkModelType[T](); err != nil {
+		return nil, err
+	}
+
+	models := []*T{}
+	if err := unmarshalMany(in, reflect.TypeOf(new(T)), newUnmarshalOptions(opts), func(model reflect.Value) {
+		models = append(models, model.Interface().(*T))
+	}); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// RuntimeUnmarshalOne is UnmarshalOne instrumented by the Runtime r; Go does
+// not allow type parameters on methods.
+func RuntimeUnmarshalOne[T any](r *Runtime, in io.Reader, opts ...UnmarshalOption) (model *T, err error) {
+	r.instrumentCall(UnmarshalStart, UnmarshalStop, func() error {
+		model, err = UnmarshalOne[T](in, opts...)
+		return err
+	})
+
+	return
+}
+
+// RuntimeUnmarshalMany is UnmarshalMany instrumented by the Runtime r.
+func RuntimeUnmarshalMany[T any](r *Runtime, in io.Reader, opts ...UnmarshalOption) (models []*T, err error) {
+	r.instrumentCall(UnmarshalStart, UnmarshalStop, func() error {
+		models, err = UnmarshalMany[T](in, opts...)
+		return err
+	})
+
+	return
+}
+
+// ModelIterator is an Iterator whose Model is a *T.
+type ModelIterator[T any] struct {
+	*Iterator
+}
+
+// ClientIterate is Client.Iterate for models of type *T:
+//
+//	it := jsonapi.ClientIterate[Blog](ctx, c, "/blogs", nil)
+//	for it.Next() {
+//		fmt.Println(it.Model().Title)
+//	}
+func ClientIterate[T any](ctx context.Context, c *Client, path string, q *Query) *ModelIterator[T] {
+	it := c.Iterate(ctx, path, reflect.TypeOf(new(T)), q)
+	if err := checkModelType[T](); err != nil {
+		it.err = err
+	}
+	return &ModelIterator[T]{it}
+}
+
+// Model returns the current model, or nil before the first call to Next or
+// after iteration has ended.
+func (it *ModelIterator[T]) Model() *T {
+	model, _ := it.Iterator.Model().(*T)
+	return model
+}
+
+// checkModelType returns ErrUnexpectedType unless T is a struct type.
+func checkModelType[T any]() error {
+	if reflect.TypeOf((*T)(nil)).Elem().Kind() != reflect.Struct {
+		return ErrUnexpectedType
+	}
+	return nil
+}
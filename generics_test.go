@@ -0,0 +1,135 @@
+//go:build go1.18
+// +build go1.18
+
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalOne(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, testBlog()); err != nil {
+		t.Fatal(err)
+	}
+	in := buf.Bytes()
+
+	expected := new(Blog)
+	if err := UnmarshalPayload(bytes.NewReader(in), expected); err != nil {
+		t.Fatal(err)
+	}
+
+	blog, err := UnmarshalOne[Blog](bytes.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, blog) {
+		t.Fatalf("Was expecting %#v, got %#v", expected, blog)
+	}
+}
+
+func TestUnmarshalMany(t *testing.T) {
+	blog, other := testBlog(), testBlog()
+	other.ID = 6
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, []*Blog{blog, other}); err != nil {
+		t.Fatal(err)
+	}
+	in := buf.Bytes()
+
+	expected, err := UnmarshalManyPayload(bytes.NewReader(in), reflect.TypeOf(new(Blog)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blogs, err := UnmarshalMany[Blog](bytes.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blogs) != len(expected) {
+		t.Fatalf("Was expecting %d blogs, got %d", len(expected), len(blogs))
+	}
+	for i := range blogs {
+		if !reflect.DeepEqual(expected[i], blogs[i]) {
+			t.Fatalf("Was expecting %#v, got %#v", expected[i], blogs[i])
+		}
+	}
+}
+
+func TestUnmarshalOne_notAStruct(t *testing.T) {
+	if _, err := UnmarshalOne[string](bytes.NewReader([]byte(`{"data": null}`))); err != ErrUnexpectedType {
+		t.Fatalf("Was expecting ErrUnexpectedType, got %v", err)
+	}
+	if _, err := UnmarshalMany[[]Blog](bytes.NewReader([]byte(`{"data": []}`))); err != ErrUnexpectedType {
+		t.Fatalf("Was expecting ErrUnexpectedType, got %v", err)
+	}
+}
+
+func TestRuntimeUnmarshalOne(t *testing.T) {
+	events, restore := recordEvents()
+	defer restore()
+
+	blog, err := RuntimeUnmarshalOne[Blog](NewRuntime(), bytes.NewReader([]byte(`{"data": {"type": "blogs", "id": "5"}}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blog.ID != 5 {
+		t.Fatalf("Was expecting blog 5, got %#v", blog)
+	}
+	if e, a := []Event{UnmarshalStart, UnmarshalStop}, *events; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the events %v, got %v", e, a)
+	}
+}
+
+func TestRuntimeUnmarshalMany(t *testing.T) {
+	events, restore := recordEvents()
+	defer restore()
+
+	blogs, err := RuntimeUnmarshalMany[Blog](NewRuntime(), bytes.NewReader([]byte(`{"data": [{"type": "blogs", "id": "5"}]}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blogs) != 1 || blogs[0].ID != 5 {
+		t.Fatalf("Was expecting blog 5, got %#v", blogs)
+	}
+	if e, a := []Event{UnmarshalStart, UnmarshalStop}, *events; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the events %v, got %v", e, a)
+	}
+}
+
+func TestRuntimeUnmarshalMany_error(t *testing.T) {
+	events, restore := recordEvents()
+	defer restore()
+
+	if _, err := RuntimeUnmarshalMany[Blog](NewRuntime(), bytes.NewReader([]byte(`{"data": [{"type": "blogs", "id": "x"}]}`))); err == nil {
+		t.Fatal("Was expecting an error")
+	}
+	// as with the Runtime methods, a failed call has no stop event
+	if e, a := []Event{UnmarshalStart}, *events; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the events %v, got %v", e, a)
+	}
+}
+
+func TestClientIterate(t *testing.T) {
+	ts := paginatedBlogs(t, 3)
+	defer ts.Close()
+
+	it := ClientIterate[Blog](context.Background(), NewClient(ts.URL), "/blogs", nil)
+	var titles []string
+	for it.Next() {
+		titles = append(titles, it.Model().Title)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := []string{"Title 1", "Title 2", "Title 3"}, titles; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the titles %v, got %v", e, a)
+	}
+
+	if it := ClientIterate[string](context.Background(), NewClient(ts.URL), "/blogs", nil); it.Next() || it.Err() != ErrUnexpectedType {
+		t.Fatalf("Was expecting ErrUnexpectedType, got %v", it.Err())
+	}
+}
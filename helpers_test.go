@@ -6,6 +6,17 @@ import (
 	"time"
 )
 
+// recordEvents sets Instrumentation to record the events of Runtime calls;
+// call the returned func to restore it.
+func recordEvents() (*[]Event, func()) {
+	var events []Event
+	prev := Instrumentation
+	Instrumentation = func(r *Runtime, e Event, guid string, d time.Duration) {
+		events = append(events, e)
+	}
+	return &events, func() { Instrumentation = prev }
+}
+
 func isJSONEqual(b1, b2 []byte) (bool, error) {
 	var i1, i2 interface{}
 	var result bool
@@ -0,0 +1,168 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// ErrMaxPages is returned by Iterator.Err when the collection has more pages
+// than the Iterator's MaxPages.
+var ErrMaxPages = errors.New("the collection has more pages than the maximum")
+
+// Iterator iterates over the resources of a paginated collection, fetching
+// each page in turn by following the "next" link of the previous one:
+//
+//	it := c.Iterate(ctx, "/blogs", reflect.TypeOf(new(Blog)), &jsonapi.Query{
+//		Page: jsonapi.Page{Strategy: jsonapi.PageNumberPagination, Size: 100},
+//	})
+//	for it.Next() {
+//		blog := it.Model().(*Blog)
+//		// ...
+//	}
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+//
+// The links are followed as the server gives them, so page, offset and cursor
+// based pagination all work alike. A relative link is resolved against the
+// URL of the page it is in. Iteration ends after a page without a "next"
+// link, an empty page, or a page whose "next" link is its own URL, or when
+// the context is done.
+type Iterator struct {
+	// MaxPages is the maximum number of pages to fetch, or 0 for no maximum.
+	// If it is reached while there are more pages, Next returns false and Err
+	// returns ErrMaxPages.
+	MaxPages int
+
+	c     *Client
+	ctx   context.Context
+	t     reflect.Type
+	next  string
+	q     *Query
+	page  *clientPage
+	pages int
+
+	models []interface{}
+	model  interface{}
+	err    error
+}
+
+// Iterate returns an Iterator over the collection at path, whose models are of
+// type t, e.g. reflect.TypeOf(new(Blog)). The query parameters of q, which may
+// be nil, are added to the request of the first page only; the "next" links
+// carry them on. If t is neither a struct pointer nor an interface, Err returns
+// ErrUnexpectedType.
+func (c *Client) Iterate(ctx context.Context, path string, t reflect.Type, q *Query) *Iterator {
+	it := &Iterator{c: c, ctx: ctx, t: t, next: path, q: q}
+	if !isModelType(t) {
+		it.err = ErrUnexpectedType
+	}
+	return it
+}
+
+// Next advances to the next model, fetching the next page if needed. It
+// returns false when there are no more models or on error; see Err.
+func (it *Iterator) Next() bool {
+	it.model = nil
+	if it.err != nil {
+		return false
+	}
+
+	for {
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		if len(it.models) > 0 {
+			break
+		}
+		if it.next == "" {
+			return false
+		}
+		if it.MaxPages > 0 && it.pages >= it.MaxPages {
+			it.err = ErrMaxPages
+			return false
+		}
+		if !it.fetch() {
+			return false
+		}
+	}
+
+	it.model, it.models = it.models[0], it.models[1:]
+	return true
+}
+
+// fetch fetches the page at it.next, and reports whether it has any models.
+// Models are kept even if the page's "next" link is invalid, so that they are
+// iterated over before Err returns the error.
+func (it *Iterator) fetch() bool {
+	var models []interface{}
+	page, err := it.c.page(it.ctx, it.next, it.q, it.t, func(model reflect.Value) {
+		models = append(models, model.Interface())
+	})
+	if err != nil {
+		// a request cut short by the context fails with a wrapped error
+		if ctxErr := it.ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		it.err = err
+		return false
+	}
+	it.pages++
+	it.page, it.models, it.q, it.next = page, models, nil, ""
+
+	// a "next" link after an empty page would only lead to more of them
+	if len(models) == 0 {
+		return false
+	}
+
+	if page.links != nil {
+		if next := linkHref((*page.links)[KeyNextPage]); next != "" {
+			u, err := page.url.Parse(next)
+			if err != nil {
+				it.err = err
+				return len(models) > 0
+			}
+			// a page linking to itself would be fetched forever
+			if u.String() != page.url.String() {
+				it.next = u.String()
+			}
+		}
+	}
+	return true
+}
+
+// Model returns the current model, a value of the Iterator's type, or nil
+// before the first call to Next or after iteration has ended.
+func (it *Iterator) Model() interface{} {
+	return it.model
+}
+
+// Meta returns the top-level meta of the page of the current model, e.g. the
+// total from MarshalPagination, or nil if the page has none.
+func (it *Iterator) Meta() *Meta {
+	if it.page == nil {
+		return nil
+	}
+	return it.page.meta
+}
+
+// Links returns the top-level links of the page of the current model, or nil
+// if the page has none.
+func (it *Iterator) Links() *Links {
+	if it.page == nil {
+		return nil
+	}
+	return it.page.links
+}
+
+// Pages returns the number of pages fetched so far.
+func (it *Iterator) Pages() int {
+	return it.pages
+}
+
+// Err returns the error that ended the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
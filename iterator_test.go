@@ -0,0 +1,276 @@
+package jsonapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// paginatedBlogs serves a collection of n blogs at /blogs, paginated with the
+// strategy of the request, or with page numbers of size 2 by default. The
+// pagination links are relative to the request.
+func paginatedBlogs(t *testing.T, n int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q, errs := ParseRequest(r)
+		if errs != nil {
+			WriteErrors(w, errs)
+			return
+		}
+
+		p := Pagination{Page: q.Page, DefaultSize: 2, Total: n, HasTotal: true}
+		var start, size int
+		switch p.Page.Strategy {
+		case OffsetPagination:
+			start, size = p.Page.Offset, p.size(p.Page.Limit)
+		case CursorPagination:
+			start, _ = strconv.Atoi(p.Page.Cursor)
+			size = p.size(p.Page.Size)
+		default:
+			p.Page.Strategy = PageNumberPagination
+			if p.Page.Number < 1 {
+				p.Page.Number = 1
+			}
+			size = p.size(p.Page.Size)
+			start = (p.Page.Number - 1) * size
+		}
+
+		blogs := []*Blog{}
+		for id := start + 1; id <= start+size && id <= n; id++ {
+			blogs = append(blogs, &Blog{ID: id, Title: "Title " + strconv.Itoa(id)})
+		}
+		if p.Page.Strategy == CursorPagination && start+size < n {
+			p.NextCursor = strconv.Itoa(start + size)
+		}
+
+		if err := WriteResource(w, http.StatusOK, blogs, MarshalPagination(r.URL, p)); err != nil {
+			t.Error(err)
+		}
+	}))
+}
+
+func TestClient_Iterate(t *testing.T) {
+	ts := paginatedBlogs(t, 5)
+	defer ts.Close()
+	c := NewClient(ts.URL)
+
+	queries := map[string]*Query{
+		"default": nil,
+		"page":    {Page: Page{Strategy: PageNumberPagination, Number: 1, Size: 2}},
+		"offset":  {Page: Page{Strategy: OffsetPagination, Offset: 0, Limit: 2}},
+		"cursor":  {Page: Page{Strategy: CursorPagination, Cursor: "0", Size: 2}},
+	}
+	for name, q := range queries {
+		t.Run(name, func(t *testing.T) {
+			it := c.Iterate(context.Background(), "/blogs", reflect.TypeOf(new(Blog)), q)
+
+			var ids []int
+			for it.Next() {
+				ids = append(ids, it.Model().(*Blog).ID)
+			}
+			if err := it.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if e, a := []int{1, 2, 3, 4, 5}, ids; !reflect.DeepEqual(e, a) {
+				t.Fatalf("Was expecting the ids %v, got %v", e, a)
+			}
+			if e, a := 3, it.Pages(); e != a {
+				t.Fatalf("Was expecting %d pages, got %d", e, a)
+			}
+			if it.Model() != nil {
+				t.Fatal("Was expecting no model after the iteration")
+			}
+		})
+	}
+}
+
+func TestClient_Iterate_meta(t *testing.T) {
+	ts := paginatedBlogs(t, 3)
+	defer ts.Close()
+
+	it := NewClient(ts.URL).Iterate(context.Background(), "/blogs", reflect.TypeOf(new(Blog)), nil)
+	if it.Meta() != nil || it.Links() != nil {
+		t.Fatal("Was expecting no meta or links before the first page")
+	}
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	if e, a := float64(3), (*it.Meta())["total"]; e != a {
+		t.Fatalf("Was expecting a total of %v, got %v", e, a)
+	}
+	if _, ok := (*it.Links())[KeyNextPage]; !ok {
+		t.Fatal("Was expecting a next link on the first page")
+	}
+}
+
+func TestClient_Iterate_maxPages(t *testing.T) {
+	ts := paginatedBlogs(t, 5)
+	defer ts.Close()
+
+	it := NewClient(ts.URL).Iterate(context.Background(), "/blogs", reflect.TypeOf(new(Blog)), nil)
+	it.MaxPages = 2
+
+	var n int
+	for it.Next() {
+		n++
+	}
+	if e, a := 4, n; e != a {
+		t.Fatalf("Was expecting %d blogs, got %d", e, a)
+	}
+	if e, a := ErrMaxPages, it.Err(); e != a {
+		t.Fatalf("Was expecting %v, got %v", e, a)
+	}
+
+	it = NewClient(ts.URL).Iterate(context.Background(), "/blogs", reflect.TypeOf(new(Blog)), &Query{
+		Page: Page{Strategy: PageNumberPagination, Number: 1, Size: 5},
+	})
+	it.MaxPages = 1
+	for it.Next() {
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Was expecting no error when the last page is the maximum, got %v", err)
+	}
+}
+
+func TestClient_Iterate_cancel(t *testing.T) {
+	ts := paginatedBlogs(t, 5)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := NewClient(ts.URL).Iterate(ctx, "/blogs", reflect.TypeOf(new(Blog)), nil)
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	cancel()
+
+	if it.Next() {
+		t.Fatal("Was expecting the iteration to stop")
+	}
+	if e, a := context.Canceled, it.Err(); e != a {
+		t.Fatalf("Was expecting %v, got %v", e, a)
+	}
+	if e, a := 1, it.Pages(); e != a {
+		t.Fatalf("Was expecting %d page, got %d", e, a)
+	}
+}
+
+func TestClient_Iterate_cancelMidStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, next := 1, Links{KeyNextPage: "/blogs?page[cursor]=a"}
+		if r.URL.Query().Get(QueryParamPageCursor) == "a" {
+			// the context is done while the second page is fetched
+			cancel()
+			id, next = 2, Links{KeyNextPage: "/blogs?page[cursor]=b"}
+		}
+		WriteResource(w, http.StatusOK, []*Blog{{ID: id}}, MarshalLinks(next))
+	}))
+	defer ts.Close()
+
+	it := NewClient(ts.URL).Iterate(ctx, "/blogs", reflect.TypeOf(new(Blog)), nil)
+	var ids []int
+	for it.Next() {
+		ids = append(ids, it.Model().(*Blog).ID)
+	}
+	if e, a := context.Canceled, it.Err(); e != a {
+		t.Fatalf("Was expecting %v, got %v", e, a)
+	}
+	if e, a := []int{1}, ids; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the ids %v, got %v", e, a)
+	}
+}
+
+func TestClient_Iterate_selfLink(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		WriteResource(w, http.StatusOK, []*Blog{{ID: 1}}, MarshalLinks(Links{KeyNextPage: "/blogs"}))
+	}))
+	defer ts.Close()
+
+	it := NewClient(ts.URL).Iterate(context.Background(), "/blogs", reflect.TypeOf(new(Blog)), nil)
+	var n int
+	for it.Next() {
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || requests != 1 {
+		t.Fatalf("Was expecting a single page with 1 blog, got %d blogs in %d requests", n, requests)
+	}
+}
+
+func TestClient_Iterate_emptyPage(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		next := "/blogs?page[cursor]=" + strconv.Itoa(requests)
+		WriteResource(w, http.StatusOK, []*Blog{}, MarshalLinks(Links{KeyNextPage: next}))
+	}))
+	defer ts.Close()
+
+	it := NewClient(ts.URL).Iterate(context.Background(), "/blogs", reflect.TypeOf(new(Blog)), nil)
+	if it.Next() {
+		t.Fatal("Was expecting no models")
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 1, requests; e != a {
+		t.Fatalf("Was expecting %d request, got %d", e, a)
+	}
+}
+
+func TestClient_Iterate_linkObject(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, next := 1, Links{KeyNextPage: Link{Href: "/blogs?page[cursor]=a"}}
+		if r.URL.Query().Get(QueryParamPageCursor) == "a" {
+			id, next = 2, Links{KeyFirstPage: "/blogs"}
+		}
+		WriteResource(w, http.StatusOK, []*Blog{{ID: id}}, MarshalLinks(next))
+	}))
+	defer ts.Close()
+
+	it := NewClient(ts.URL).Iterate(context.Background(), "/blogs", reflect.TypeOf(new(Blog)), nil)
+	var ids []int
+	for it.Next() {
+		ids = append(ids, it.Model().(*Blog).ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := []int{1, 2}, ids; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the ids %v, got %v", e, a)
+	}
+}
+
+func TestClient_Iterate_error(t *testing.T) {
+	ts := paginatedBlogs(t, 5)
+	defer ts.Close()
+
+	it := NewClient(ts.URL).Iterate(context.Background(), "/blogs?page[number]=0", reflect.TypeOf(new(Blog)), nil)
+	if it.Next() {
+		t.Fatal("Was expecting no models")
+	}
+	if errs, ok := it.Err().(ErrorObjects); !ok || errs.StatusCode() != http.StatusBadRequest {
+		t.Fatalf("Was expecting a 400 error response, got %v", it.Err())
+	}
+}
+
+func TestClient_Iterate_unexpectedType(t *testing.T) {
+	ts := paginatedBlogs(t, 5)
+	defer ts.Close()
+
+	it := NewClient(ts.URL).Iterate(context.Background(), "/blogs", reflect.TypeOf(Blog{}), nil)
+	if it.Next() {
+		t.Fatal("Was expecting no models")
+	}
+	if it.Err() != ErrUnexpectedType {
+		t.Fatalf("Was expecting ErrUnexpectedType, got %v", it.Err())
+	}
+}
@@ -145,6 +145,20 @@ func (b *Blog) JSONAPIRelationshipMeta(relation string) *Meta {
 	return nil
 }
 
+type Blogs []*Blog
+
+func (b Blogs) JSONAPILinks() *Links {
+	return &Links{
+		"self": "https://example.com/api/blogs",
+	}
+}
+
+func (b Blogs) JSONAPIMeta() *Meta {
+	return &Meta{
+		"query": "all blogs",
+	}
+}
+
 type BadComment struct {
 	ID   uint64 `jsonapi:"primary,bad-comment"`
 	Body string `jsonapi:"attr,body"`
@@ -174,3 +188,51 @@ type Vehicle struct {
 	Engine              // every car must have an engine
 	*BlockHeater        // not every car will have a block heater
 }
+
+// Actor is implemented by the models of the polymorphic relationships of
+// Activity.
+type Actor interface {
+	isActor()
+}
+
+type User struct {
+	ID   int    `jsonapi:"primary,users"`
+	Name string `jsonapi:"attr,name"`
+}
+
+func (*User) isActor() {}
+
+type Bot struct {
+	ID      int    `jsonapi:"primary,bots"`
+	Version string `jsonapi:"attr,version"`
+}
+
+func (*Bot) isActor() {}
+
+// Guest implements Actor with a value receiver, so that a Guest struct can be
+// assigned to an Actor field.
+type Guest struct {
+	ID int `jsonapi:"primary,guests"`
+}
+
+func (Guest) isActor() {}
+
+type Activity struct {
+	ID           int     `jsonapi:"primary,activities"`
+	Actor        Actor   `jsonapi:"relation,actor"`
+	Participants []Actor `jsonapi:"relation,participants"`
+}
+
+type Article struct {
+	ID           string    `jsonapi:"primary,articles"`
+	Lid          string    `jsonapi:"lid"`
+	Title        string    `jsonapi:"attr,title"`
+	Author       *Person   `jsonapi:"relation,author"`
+	Contributors []*Person `jsonapi:"relation,contributors"`
+}
+
+type Person struct {
+	ID   string `jsonapi:"primary,people"`
+	Lid  string `jsonapi:"lid"`
+	Name string `jsonapi:"attr,name"`
+}
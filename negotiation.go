@@ -0,0 +1,312 @@
+package jsonapi
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	headerAccept      = "Accept"
+	headerContentType = "Content-Type"
+
+	mediaTypeParamExt     = "ext"
+	mediaTypeParamProfile = "profile"
+	acceptParamQuality    = "q"
+)
+
+// Negotiation holds the extensions and profiles negotiated for a request by
+// Negotiate or NegotiateRequest.
+type Negotiation struct {
+	// RequestExtensions and RequestProfiles are applied to the request
+	// document, as given by the ext and profile parameters of its Content-Type.
+	RequestExtensions []string
+	RequestProfiles   []string
+	// Extensions and Profiles are to be applied to the response document, as
+	// given by the preferred acceptable instance of the JSON API media type in
+	// the Accept header.
+	Extensions []string
+	Profiles   []string
+}
+
+// MediaType returns the JSON API media type with the negotiated response
+// extensions and profiles as its parameters, for the Content-Type header of
+// the response. It is MediaType if n is nil.
+func (n *Negotiation) MediaType() string {
+	if n == nil {
+		return MediaType
+	}
+	return formatMediaType(n.Extensions, n.Profiles)
+}
+
+func formatMediaType(extensions, profiles []string) string {
+	params := map[string]string{}
+	if len(extensions) > 0 {
+		params[mediaTypeParamExt] = strings.Join(extensions, " ")
+	}
+	if len(profiles) > 0 {
+		params[mediaTypeParamProfile] = strings.Join(profiles, " ")
+	}
+	return mime.FormatMediaType(MediaType, params)
+}
+
+// NegotiateOption configures Negotiate and NegotiateRequest.
+type NegotiateOption func(*negotiateOptions)
+
+type negotiateOptions struct {
+	extensions map[string]bool
+	profiles   map[string]bool
+}
+
+func newNegotiateOptions(opts []NegotiateOption) *negotiateOptions {
+	o := &negotiateOptions{
+		extensions: map[string]bool{},
+		profiles:   map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// NegotiateExtensions gives the URIs of the extensions the server supports,
+// such as AtomicExtension. A request with any other extension is rejected.
+func NegotiateExtensions(uris ...string) NegotiateOption {
+	return func(o *negotiateOptions) {
+		for _, uri := range uris {
+			o.extensions[uri] = true
+		}
+	}
+}
+
+// NegotiateProfiles gives the URIs of the profiles the server supports. Other
+// profiles are ignored, as the specification allows.
+func NegotiateProfiles(uris ...string) NegotiateOption {
+	return func(o *negotiateOptions) {
+		for _, uri := range uris {
+			o.profiles[uri] = true
+		}
+	}
+}
+
+type negotiationContextKey struct{}
+
+// NegotiationFromContext returns the Negotiation that Negotiate stored in the
+// context of a request, or nil if there is none.
+func NegotiationFromContext(ctx context.Context) *Negotiation {
+	n, _ := ctx.Value(negotiationContextKey{}).(*Negotiation)
+	return n
+}
+
+// Negotiate returns middleware that implements the content negotiation rules
+// of the specification before calling next:
+//
+//	http.Handle("/blogs", jsonapi.Negotiate(blogsHandler, jsonapi.NegotiateExtensions(jsonapi.AtomicExtension)))
+//
+// A request whose Content-Type is the JSON API media type with parameters
+// other than ext and profile, with malformed parameters, or with an
+// unsupported extension, gets a 415
+// Unsupported Media Type response. A request whose Accept header has
+// instances of the JSON API media type, none of which is acceptable, gets a
+// 406 Not Acceptable response. Both are written as an errors document. Other
+// requests are passed to next with the Negotiation in their context; see
+// NegotiationFromContext.
+//
+// http://jsonapi.org/format/#content-negotiation
+func Negotiate(next http.Handler, opts ...NegotiateOption) http.Handler {
+	o := newNegotiateOptions(opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, errs := o.negotiate(r.Header)
+		if len(errs) > 0 {
+			w.Header().Set(headerContentType, MediaType)
+			w.WriteHeader(ErrorObjects(errs).StatusCode())
+			MarshalErrors(w, errs)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), negotiationContextKey{}, n)))
+	})
+}
+
+// NegotiateRequest negotiates the extensions and profiles of r as Negotiate
+// does, for handlers that are not wrapped by it. The errors have a status of
+// "415" or "406".
+func NegotiateRequest(r *http.Request, opts ...NegotiateOption) (*Negotiation, []*ErrorObject) {
+	return newNegotiateOptions(opts).negotiate(r.Header)
+}
+
+func (o *negotiateOptions) negotiate(h http.Header) (*Negotiation, []*ErrorObject) {
+	n := new(Negotiation)
+
+	if contentType := h.Get(headerContentType); contentType != "" {
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			// other malformed media types are left to the handler
+			if isMediaType(contentType) {
+				return nil, []*ErrorObject{newNegotiationError("415", headerContentType,
+					fmt.Sprintf("The %s media type is malformed: %v", MediaType, err))}
+			}
+		} else if mediaType == MediaType {
+			for name := range params {
+				if name != mediaTypeParamExt && name != mediaTypeParamProfile {
+					return nil, []*ErrorObject{newNegotiationError("415", headerContentType,
+						fmt.Sprintf("The %s media type parameter %q is not supported", MediaType, name))}
+				}
+			}
+			n.RequestExtensions = splitURIs(params[mediaTypeParamExt])
+			for _, ext := range n.RequestExtensions {
+				if !o.extensions[ext] {
+					return nil, []*ErrorObject{newNegotiationError("415", headerContentType,
+						fmt.Sprintf("The extension %q is not supported", ext))}
+				}
+			}
+			n.RequestProfiles = o.supportedProfiles(params[mediaTypeParamProfile])
+		}
+	}
+
+	var hasMediaType bool
+	for _, accepted := range acceptedMediaTypes(h[headerAccept]) {
+		if accepted.mediaType != MediaType {
+			continue
+		}
+		hasMediaType = true
+		if accepted.quality == 0 || !o.acceptable(accepted.params) {
+			continue
+		}
+
+		n.Extensions = splitURIs(accepted.params[mediaTypeParamExt])
+		n.Profiles = o.supportedProfiles(accepted.params[mediaTypeParamProfile])
+		return n, nil
+	}
+	if hasMediaType {
+		return nil, []*ErrorObject{newNegotiationError("406", headerAccept,
+			fmt.Sprintf("None of the accepted instances of %s can be served", MediaType))}
+	}
+
+	return n, nil
+}
+
+// isMediaType reports whether the media type of the header value v, which may
+// not parse, is the JSON API media type.
+func isMediaType(v string) bool {
+	return strings.EqualFold(strings.TrimSpace(strings.SplitN(v, ";", 2)[0]), MediaType)
+}
+
+// acceptable reports whether an instance of the JSON API media type with the
+// params can be served: its only parameters are ext and profile, and all of
+// its extensions are supported.
+func (o *negotiateOptions) acceptable(params map[string]string) bool {
+	for name := range params {
+		if name != mediaTypeParamExt && name != mediaTypeParamProfile {
+			return false
+		}
+	}
+	for _, ext := range strings.Fields(params[mediaTypeParamExt]) {
+		if !o.extensions[ext] {
+			return false
+		}
+	}
+	return true
+}
+
+// supportedProfiles returns the supported profiles of the space-separated
+// profile parameter.
+func (o *negotiateOptions) supportedProfiles(param string) []string {
+	var profiles []string
+	for _, profile := range splitURIs(param) {
+		if o.profiles[profile] {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles
+}
+
+// splitURIs splits the space-separated URIs of an ext or profile parameter;
+// it returns nil if there are none.
+func splitURIs(param string) []string {
+	if uris := strings.Fields(param); len(uris) > 0 {
+		return uris
+	}
+	return nil
+}
+
+// acceptedMediaType is a media range of the Accept header.
+type acceptedMediaType struct {
+	mediaType string
+	// params holds the media type parameters, without the quality.
+	params  map[string]string
+	quality float64
+}
+
+// acceptedMediaTypes parses the media ranges of the Accept header values, in
+// order of preference. Malformed ranges are skipped.
+func acceptedMediaTypes(values []string) []acceptedMediaType {
+	var accepted []acceptedMediaType
+	for _, value := range values {
+		for _, mediaRange := range splitAccept(value) {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil {
+				continue
+			}
+
+			quality := 1.0
+			if q, ok := params[acceptParamQuality]; ok {
+				if quality, err = strconv.ParseFloat(q, 64); err != nil {
+					continue
+				}
+				delete(params, acceptParamQuality)
+			}
+			accepted = append(accepted, acceptedMediaType{mediaType: mediaType, params: params, quality: quality})
+		}
+	}
+
+	sort.Stable(byQuality(accepted))
+	return accepted
+}
+
+// byQuality sorts accepted media types by descending quality.
+type byQuality []acceptedMediaType
+
+func (a byQuality) Len() int           { return len(a) }
+func (a byQuality) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byQuality) Less(i, j int) bool { return a[i].quality > a[j].quality }
+
+// splitAccept splits an Accept header value at the commas between media
+// ranges, leaving those within quoted parameter values.
+func splitAccept(value string) []string {
+	var ranges []string
+	var quoted bool
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				ranges = append(ranges, strings.TrimSpace(value[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(ranges, strings.TrimSpace(value[start:]))
+}
+
+func newNegotiationError(status, header, detail string) *ErrorObject {
+	title := "Unsupported Media Type"
+	if status == "406" {
+		title = "Not Acceptable"
+	}
+	return &ErrorObject{
+		Title:  title,
+		Detail: detail,
+		Status: status,
+		Source: &ErrorSource{Header: header},
+	}
+}
@@ -0,0 +1,197 @@
+package jsonapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	const profile = "https://example.com/profiles/timestamps"
+	atomic := `; ext="` + AtomicExtension + `"`
+
+	tests := []struct {
+		name        string
+		contentType string
+		accept      []string
+		status      int
+		negotiation *Negotiation
+	}{
+		{
+			name:        "plain",
+			contentType: MediaType,
+			accept:      []string{MediaType},
+			status:      http.StatusOK,
+			negotiation: &Negotiation{},
+		},
+		{
+			name:        "no headers",
+			status:      http.StatusOK,
+			negotiation: &Negotiation{},
+		},
+		{
+			name:        "other media types",
+			contentType: "application/json; charset=utf-8",
+			accept:      []string{"text/html, */*"},
+			status:      http.StatusOK,
+			negotiation: &Negotiation{},
+		},
+		{
+			name:        "content type parameter",
+			contentType: MediaType + "; charset=utf-8",
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "malformed content type parameters",
+			contentType: MediaType + `; ext`,
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "malformed content type extension",
+			contentType: MediaType + `; ext="` + AtomicExtension,
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "malformed other media type",
+			contentType: "application/json; charset",
+			status:      http.StatusOK,
+			negotiation: &Negotiation{},
+		},
+		{
+			name:        "unsupported content type extension",
+			contentType: MediaType + `; ext="https://example.com/ext/unknown"`,
+			status:      http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "extensions and profiles",
+			contentType: MediaType + atomic,
+			accept:      []string{MediaType + `; profile="` + profile + ` https://example.com/unknown"` + atomic},
+			status:      http.StatusOK,
+			negotiation: &Negotiation{
+				RequestExtensions: []string{AtomicExtension},
+				Extensions:        []string{AtomicExtension},
+				Profiles:          []string{profile},
+			},
+		},
+		{
+			name:   "all accept instances have parameters",
+			accept: []string{MediaType + "; charset=utf-8", MediaType + "; version=1"},
+			status: http.StatusNotAcceptable,
+		},
+		{
+			name:   "all accept instances have unsupported extensions",
+			accept: []string{MediaType + `; ext="https://example.com/ext/unknown"`},
+			status: http.StatusNotAcceptable,
+		},
+		{
+			name:   "not acceptable quality",
+			accept: []string{MediaType + ";q=0"},
+			status: http.StatusNotAcceptable,
+		},
+		{
+			name:        "one acceptable accept instance",
+			accept:      []string{MediaType + "; charset=utf-8, " + MediaType + atomic + ";q=0.5"},
+			status:      http.StatusOK,
+			negotiation: &Negotiation{Extensions: []string{AtomicExtension}},
+		},
+		{
+			name:        "preferred accept instance",
+			accept:      []string{MediaType + ";q=0.5, " + MediaType + atomic},
+			status:      http.StatusOK,
+			negotiation: &Negotiation{Extensions: []string{AtomicExtension}},
+		},
+	}
+
+	for _, test := range tests {
+		var negotiation *Negotiation
+		handler := Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			negotiation = NegotiationFromContext(r.Context())
+		}), NegotiateExtensions(AtomicExtension), NegotiateProfiles(profile))
+
+		r := httptest.NewRequest(http.MethodPost, "/blogs", nil)
+		if test.contentType != "" {
+			r.Header.Set(headerContentType, test.contentType)
+		}
+		for _, accept := range test.accept {
+			r.Header.Add(headerAccept, accept)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, r)
+
+		if e, a := test.status, rr.Code; e != a {
+			t.Fatalf("%s: Was expecting a status of %d, got %d", test.name, e, a)
+		}
+		if !reflect.DeepEqual(test.negotiation, negotiation) {
+			t.Fatalf("%s: Was expecting %#v, got %#v", test.name, test.negotiation, negotiation)
+		}
+		if test.status == http.StatusOK {
+			// the Content-Type of other responses is left to the handler
+			if a := rr.Header().Get(headerContentType); a != "" {
+				t.Fatalf("%s: Was expecting no Content-Type, got %q", test.name, a)
+			}
+			continue
+		}
+
+		if e, a := MediaType, rr.Header().Get(headerContentType); e != a {
+			t.Fatalf("%s: Was expecting a Content-Type of %q, got %q", test.name, e, a)
+		}
+		errs, err := UnmarshalErrors(rr.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) != 1 || errs[0].StatusCode() != test.status {
+			t.Fatalf("%s: Was expecting an error with a status of %d, got %v", test.name, test.status, errs)
+		}
+	}
+}
+
+func TestNegotiateRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/operations", nil)
+	r.Header.Set(headerContentType, MediaType+`; ext="`+AtomicExtension+`"`)
+	r.Header.Set(headerAccept, MediaType+`; ext="`+AtomicExtension+`"`)
+
+	n, errs := NegotiateRequest(r, NegotiateExtensions(AtomicExtension))
+	if errs != nil {
+		t.Fatal(ErrorObjects(errs))
+	}
+	expected := &Negotiation{
+		RequestExtensions: []string{AtomicExtension},
+		Extensions:        []string{AtomicExtension},
+	}
+	if !reflect.DeepEqual(expected, n) {
+		t.Fatalf("Was expecting %#v, got %#v", expected, n)
+	}
+
+	// the extension is unsupported without the option
+	n, errs = NegotiateRequest(r)
+	if n != nil || len(errs) != 1 || errs[0].Status != "415" {
+		t.Fatalf("Was expecting a 415 error, got %v", ErrorObjects(errs))
+	}
+
+	r.Header.Set(headerContentType, MediaType+"; ext=")
+	n, errs = NegotiateRequest(r, NegotiateExtensions(AtomicExtension))
+	if n != nil || len(errs) != 1 || errs[0].Status != "415" || errs[0].Source.Header != headerContentType {
+		t.Fatalf("Was expecting a 415 error for a malformed Content-Type, got %v", ErrorObjects(errs))
+	}
+
+	r.Header.Set(headerContentType, MediaType)
+	r.Header.Set(headerAccept, MediaType+"; version=1")
+	n, errs = NegotiateRequest(r)
+	if n != nil || len(errs) != 1 || errs[0].Status != "406" {
+		t.Fatalf("Was expecting a 406 error, got %v", ErrorObjects(errs))
+	}
+}
+
+func TestNegotiation_MediaType(t *testing.T) {
+	var n *Negotiation
+	if e, a := MediaType, n.MediaType(); e != a {
+		t.Fatalf("Was expecting %q, got %q", e, a)
+	}
+
+	n = &Negotiation{Extensions: []string{AtomicExtension}}
+	if e, a := MediaType+`; ext="`+AtomicExtension+`"`, n.MediaType(); e != a {
+		t.Fatalf("Was expecting %q, got %q", e, a)
+	}
+}
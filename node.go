@@ -10,10 +10,11 @@ type Payloader interface {
 // OnePayload is used to represent a generic JSON API payload where a single
 // resource (Node) was included as an {} in the "data" key
 type OnePayload struct {
-	Data     *Node   `json:"data"`
-	Included []*Node `json:"included,omitempty"`
-	Links    *Links  `json:"links,omitempty"`
-	Meta     *Meta   `json:"meta,omitempty"`
+	Data     *Node          `json:"data"`
+	Included []*Node        `json:"included,omitempty"`
+	Links    *Links         `json:"links,omitempty"`
+	Meta     *Meta          `json:"meta,omitempty"`
+	JSONAPI  *JSONAPIObject `json:"jsonapi,omitempty"`
 }
 
 func (p *OnePayload) clearIncluded() {
@@ -23,10 +24,11 @@ func (p *OnePayload) clearIncluded() {
 // ManyPayload is used to represent a generic JSON API payload where many
 // resources (Nodes) were included in an [] in the "data" key
 type ManyPayload struct {
-	Data     []*Node `json:"data"`
-	Included []*Node `json:"included,omitempty"`
-	Links    *Links  `json:"links,omitempty"`
-	Meta     *Meta   `json:"meta,omitempty"`
+	Data     []*Node        `json:"data"`
+	Included []*Node        `json:"included,omitempty"`
+	Links    *Links         `json:"links,omitempty"`
+	Meta     *Meta          `json:"meta,omitempty"`
+	JSONAPI  *JSONAPIObject `json:"jsonapi,omitempty"`
 }
 
 func (p *ManyPayload) clearIncluded() {
@@ -38,10 +40,14 @@ type Node struct {
 	Type          string                 `json:"type"`
 	ID            string                 `json:"id,omitempty"`
 	ClientID      string                 `json:"client-id,omitempty"`
+	Lid           string                 `json:"lid,omitempty"`
 	Attributes    attributes             `json:"attributes,omitempty"`
 	Relationships map[string]interface{} `json:"relationships,omitempty"`
 	Links         *Links                 `json:"links,omitempty"`
 	Meta          *Meta                  `json:"meta,omitempty"`
+
+	// pointer is the JSON Pointer to the node within an unmarshaled payload
+	pointer string
 }
 
 func (n *Node) handleNodeErrors() {
@@ -115,6 +121,10 @@ func (n *Node) mergeFunc(node *Node, attrSetter func(attrs attributes)) {
 		n.ClientID = node.ClientID
 	}
 
+	if node.Lid != "" {
+		n.Lid = node.Lid
+	}
+
 	if n.Attributes == nil && node.Attributes != nil {
 		n.Attributes = make(map[string]interface{})
 	}
@@ -179,6 +189,16 @@ type Link struct {
 	Meta Meta   `json:"meta,omitempty"`
 }
 
+// JSONAPIObject is used to represent the top-level `jsonapi` object, which
+// describes the server's implementation.
+// http://jsonapi.org/format/#document-jsonapi-object
+type JSONAPIObject struct {
+	Version string   `json:"version,omitempty"`
+	Ext     []string `json:"ext,omitempty"`
+	Profile []string `json:"profile,omitempty"`
+	Meta    *Meta    `json:"meta,omitempty"`
+}
+
 // Linkable is used to include document links in response data
 // e.g. {"self": "http://example.com/posts/1"}
 type Linkable interface {
@@ -240,6 +260,16 @@ func deepCopyNode(n *Node) *Node {
 	return &copy
 }
 
+// nodeKey returns the key of n among the included resources: its type and id,
+// or its type and lid if it has no id, as for a resource created in the same
+// request.
+func nodeKey(n *Node) string {
+	if n.ID == "" && n.Lid != "" {
+		return fmt.Sprintf("%s,lid:%s", n.Type, n.Lid)
+	}
+	return fmt.Sprintf("%s,%s", n.Type, n.ID)
+}
+
 // nodeError is used to track errors in processing Node related values
 type nodeError interface {
 	Error() string
@@ -0,0 +1,183 @@
+package jsonapi
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// Pagination describes the page of a collection being written, and is used to
+// build the pagination links of the document.
+//
+// http://jsonapi.org/format/#fetching-pagination
+type Pagination struct {
+	// Page holds the page parameters of the request, e.g. as parsed by
+	// ParseQuery. If the request had no page parameters, set Page.Strategy to
+	// the strategy used by the server.
+	Page Page
+	// DefaultSize is the page size, or limit, used when the request does not
+	// give one.
+	DefaultSize int
+	// Total is the number of resources in the whole collection; it is only
+	// used if HasTotal is true. The "last" link requires a total.
+	Total    int
+	HasTotal bool
+	// Count is the number of resources in the page. Without a total, page and
+	// offset based strategies give a "next" link when the page is full, i.e.
+	// when Count is the page size; a client then stops at an empty page.
+	Count int
+	// PrevCursor and NextCursor are the cursors of the previous and next pages
+	// of a cursor based strategy; the corresponding link is omitted when a
+	// cursor is empty.
+	PrevCursor string
+	NextCursor string
+}
+
+// Links returns the "first", "last", "prev" and "next" links of the page,
+// relative to u, the URL of the request. Query parameters other than the page
+// parameters are preserved. Links that do not apply, e.g. "prev" on the first
+// page, are omitted.
+//
+// The links can be given to MarshalLinks, or be returned by the
+// JSONAPIRelationshipLinks method of a RelationshipLinkable model.
+func (p Pagination) Links(u *url.URL) Links {
+	links := Links{}
+
+	switch p.Page.Strategy {
+	case PageNumberPagination:
+		size := p.size(p.Page.Size)
+		number := p.Page.Number
+		if number < 1 {
+			number = 1
+		}
+		link := func(number int) string {
+			params := map[string]string{QueryParamPageNumber: strconv.Itoa(number)}
+			if size > 0 {
+				params[QueryParamPageSize] = strconv.Itoa(size)
+			}
+			return pageURL(u, params)
+		}
+
+		links[KeyFirstPage] = link(1)
+		if number > 1 {
+			links[KeyPreviousPage] = link(number - 1)
+		}
+		if p.HasTotal && size > 0 {
+			last := (p.Total + size - 1) / size
+			if last < 1 {
+				last = 1
+			}
+			links[KeyLastPage] = link(last)
+			if number < last {
+				links[KeyNextPage] = link(number + 1)
+			}
+		} else if p.isFull(size) {
+			links[KeyNextPage] = link(number + 1)
+		}
+	case OffsetPagination:
+		limit := p.size(p.Page.Limit)
+		offset := p.Page.Offset
+		link := func(offset int) string {
+			params := map[string]string{QueryParamPageOffset: strconv.Itoa(offset)}
+			if limit > 0 {
+				params[QueryParamPageLimit] = strconv.Itoa(limit)
+			}
+			return pageURL(u, params)
+		}
+
+		links[KeyFirstPage] = link(0)
+		if offset > 0 {
+			prev := offset - limit
+			if prev < 0 || limit == 0 {
+				prev = 0
+			}
+			links[KeyPreviousPage] = link(prev)
+		}
+		if p.HasTotal && limit > 0 {
+			last := 0
+			if p.Total > 0 {
+				last = (p.Total - 1) / limit * limit
+			}
+			links[KeyLastPage] = link(last)
+			if offset+limit < p.Total {
+				links[KeyNextPage] = link(offset + limit)
+			}
+		} else if p.isFull(limit) {
+			links[KeyNextPage] = link(offset + limit)
+		}
+	case CursorPagination:
+		size := p.size(p.Page.Size)
+		link := func(cursor string) string {
+			params := map[string]string{}
+			if cursor != "" {
+				params[QueryParamPageCursor] = cursor
+			}
+			if size > 0 {
+				params[QueryParamPageSize] = strconv.Itoa(size)
+			}
+			return pageURL(u, params)
+		}
+
+		links[KeyFirstPage] = link("")
+		if p.PrevCursor != "" {
+			links[KeyPreviousPage] = link(p.PrevCursor)
+		}
+		if p.NextCursor != "" {
+			links[KeyNextPage] = link(p.NextCursor)
+		}
+	}
+
+	return links
+}
+
+// Meta returns the pagination meta of the page, i.e. the "total" number of
+// resources if it is known.
+func (p Pagination) Meta() Meta {
+	meta := Meta{}
+	if p.HasTotal {
+		meta["total"] = p.Total
+	}
+	return meta
+}
+
+// MarshalPagination adds the pagination links of p, relative to the request
+// URL u, to the top-level "links" of the document, and its "total" to the
+// top-level "meta".
+func MarshalPagination(u *url.URL, p Pagination) MarshalOption {
+	links, meta := MarshalLinks(p.Links(u)), MarshalMeta(p.Meta())
+	return func(o *marshalOptions) {
+		links(o)
+		meta(o)
+	}
+}
+
+func (p Pagination) size(requested int) int {
+	if requested > 0 {
+		return requested
+	}
+	return p.DefaultSize
+}
+
+// isFull reports whether a page of the given size, without a total, is full,
+// so that there may be a next page.
+func (p Pagination) isFull(size int) bool {
+	return !p.HasTotal && size > 0 && p.Count >= size
+}
+
+// pageURL returns u with its page query parameters replaced by params.
+func pageURL(u *url.URL, params map[string]string) string {
+	query := u.Query()
+	for _, param := range []string{
+		QueryParamPageNumber, QueryParamPageSize,
+		QueryParamPageOffset, QueryParamPageLimit,
+		QueryParamPageCursor,
+	} {
+		query.Del(param)
+	}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+
+	link := *u
+	link.RawQuery = query.Encode()
+	return link.String()
+}
@@ -0,0 +1,159 @@
+package jsonapi
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestPaginationLinks(t *testing.T) {
+	u, err := url.Parse("https://example.com/api/blogs?sort=-title&page[number]=2&page[size]=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := func(query string) string {
+		values, _ := url.ParseQuery(query)
+		return "https://example.com/api/blogs?" + values.Encode()
+	}
+
+	scenarios := []struct {
+		name       string
+		pagination Pagination
+		expected   Links
+	}{
+		{
+			name: "page number with total",
+			pagination: Pagination{
+				Page:     Page{Strategy: PageNumberPagination, Number: 2, Size: 10},
+				Total:    35,
+				HasTotal: true,
+			},
+			expected: Links{
+				KeyFirstPage:    link("sort=-title&page[number]=1&page[size]=10"),
+				KeyPreviousPage: link("sort=-title&page[number]=1&page[size]=10"),
+				KeyNextPage:     link("sort=-title&page[number]=3&page[size]=10"),
+				KeyLastPage:     link("sort=-title&page[number]=4&page[size]=10"),
+			},
+		},
+		{
+			name: "page number without total",
+			pagination: Pagination{
+				Page: Page{Strategy: PageNumberPagination, Number: 1},
+			},
+			expected: Links{
+				KeyFirstPage: link("sort=-title&page[number]=1"),
+			},
+		},
+		{
+			name: "full page number without total",
+			pagination: Pagination{
+				Page:  Page{Strategy: PageNumberPagination, Number: 2, Size: 10},
+				Count: 10,
+			},
+			expected: Links{
+				KeyFirstPage:    link("sort=-title&page[number]=1&page[size]=10"),
+				KeyPreviousPage: link("sort=-title&page[number]=1&page[size]=10"),
+				KeyNextPage:     link("sort=-title&page[number]=3&page[size]=10"),
+			},
+		},
+		{
+			name: "partial page number without total",
+			pagination: Pagination{
+				Page:  Page{Strategy: PageNumberPagination, Number: 2, Size: 10},
+				Count: 4,
+			},
+			expected: Links{
+				KeyFirstPage:    link("sort=-title&page[number]=1&page[size]=10"),
+				KeyPreviousPage: link("sort=-title&page[number]=1&page[size]=10"),
+			},
+		},
+		{
+			name: "full offset without total",
+			pagination: Pagination{
+				Page:  Page{Strategy: OffsetPagination, Offset: 0, Limit: 20},
+				Count: 20,
+			},
+			expected: Links{
+				KeyFirstPage: link("sort=-title&page[offset]=0&page[limit]=20"),
+				KeyNextPage:  link("sort=-title&page[offset]=20&page[limit]=20"),
+			},
+		},
+		{
+			name: "offset with default limit",
+			pagination: Pagination{
+				Page:        Page{Strategy: OffsetPagination, Offset: 5},
+				DefaultSize: 20,
+				Total:       60,
+				HasTotal:    true,
+			},
+			expected: Links{
+				KeyFirstPage:    link("sort=-title&page[offset]=0&page[limit]=20"),
+				KeyPreviousPage: link("sort=-title&page[offset]=0&page[limit]=20"),
+				KeyNextPage:     link("sort=-title&page[offset]=25&page[limit]=20"),
+				KeyLastPage:     link("sort=-title&page[offset]=40&page[limit]=20"),
+			},
+		},
+		{
+			name: "offset on the last page",
+			pagination: Pagination{
+				Page:     Page{Strategy: OffsetPagination, Offset: 40, Limit: 20},
+				Total:    60,
+				HasTotal: true,
+			},
+			expected: Links{
+				KeyFirstPage:    link("sort=-title&page[offset]=0&page[limit]=20"),
+				KeyPreviousPage: link("sort=-title&page[offset]=20&page[limit]=20"),
+				KeyLastPage:     link("sort=-title&page[offset]=40&page[limit]=20"),
+			},
+		},
+		{
+			name: "cursor",
+			pagination: Pagination{
+				Page:       Page{Strategy: CursorPagination, Cursor: "b", Size: 5},
+				PrevCursor: "a",
+				NextCursor: "c",
+			},
+			expected: Links{
+				KeyFirstPage:    link("sort=-title&page[size]=5"),
+				KeyPreviousPage: link("sort=-title&page[cursor]=a&page[size]=5"),
+				KeyNextPage:     link("sort=-title&page[cursor]=c&page[size]=5"),
+			},
+		},
+		{
+			name:       "no pagination",
+			pagination: Pagination{},
+			expected:   Links{},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			links := scenario.pagination.Links(u)
+			if !reflect.DeepEqual(scenario.expected, links) {
+				t.Fatalf("Expected:\n%#v\nto equal:\n%#v", links, scenario.expected)
+			}
+		})
+	}
+}
+
+func TestMarshalPagination(t *testing.T) {
+	u, _ := url.Parse("/blogs")
+	p := Pagination{
+		Page:     Page{Strategy: PageNumberPagination, Number: 1, Size: 2},
+		Total:    3,
+		HasTotal: true,
+	}
+
+	payload, err := Marshal([]*Blog{testBlog(), testBlog()}, MarshalPagination(u, p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	many := payload.(*ManyPayload)
+
+	if e, a := "/blogs?page%5Bnumber%5D=2&page%5Bsize%5D=2", (*many.Links)[KeyNextPage]; e != a {
+		t.Fatalf("Was expecting the next link %q, got %q", e, a)
+	}
+	if e, a := 3, (*many.Meta)["total"]; e != a {
+		t.Fatalf("Was expecting a total of %d, got %v", e, a)
+	}
+}
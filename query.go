@@ -0,0 +1,382 @@
+package jsonapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// QueryParamInclude is the JSON API query parameter holding the
+	// comma-separated include paths of a request
+	//
+	// http://jsonapi.org/format/#fetching-includes
+	QueryParamInclude = "include"
+	// QueryParamSort is the JSON API query parameter holding the
+	// comma-separated sort fields of a request
+	//
+	// http://jsonapi.org/format/#fetching-sorting
+	QueryParamSort = "sort"
+
+	queryParamFields = "fields"
+	queryParamFilter = "filter"
+	queryParamPage   = "page"
+)
+
+// PaginationStrategy identifies the pagination strategy used by a request.
+type PaginationStrategy int
+
+const (
+	// NoPagination is used when a request has no page parameters.
+	NoPagination PaginationStrategy = iota
+	// PageNumberPagination is a page based strategy using QueryParamPageNumber
+	// and QueryParamPageSize.
+	PageNumberPagination
+	// OffsetPagination is an offset based strategy using QueryParamPageOffset
+	// and QueryParamPageLimit.
+	OffsetPagination
+	// CursorPagination is a cursor based strategy using QueryParamPageCursor
+	// and, optionally, QueryParamPageSize.
+	CursorPagination
+)
+
+// Query is the parsed form of the JSON API query parameters of a request.
+type Query struct {
+	// Include holds the dot-separated include paths, e.g. "posts.comments".
+	// It is nil when the request has no include parameter, and empty when the
+	// client asked for no related resources at all.
+	Include []string
+	// Fields holds the sparse fieldsets keyed by resource type.
+	Fields map[string][]string
+	// Sort holds the sort fields in order of precedence.
+	Sort []SortField
+	// Filter holds the values of the "filter[...]" parameters keyed by the
+	// text within the brackets; the value of a bare "filter" parameter is
+	// keyed by "". Nested members, as in "filter[author][name]", are not
+	// supported and are reported as errors.
+	Filter map[string][]string
+	// Page holds the pagination parameters.
+	Page Page
+}
+
+// SortField is a single field of the "sort" query parameter.
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// String returns the field as written in the "sort" query parameter.
+func (s SortField) String() string {
+	if s.Descending {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// Page holds the pagination parameters of a request. Only the members of the
+// given Strategy are set.
+type Page struct {
+	Strategy PaginationStrategy
+	// Number is the 1-based page number of PageNumberPagination; it defaults
+	// to 1 when only QueryParamPageSize is given.
+	Number int
+	// Size is the page size of PageNumberPagination and CursorPagination; it
+	// is 0 when not given.
+	Size int
+	// Offset and Limit are used by OffsetPagination; Limit is 0 when not
+	// given.
+	Offset int
+	Limit  int
+	// Cursor is used by CursorPagination.
+	Cursor string
+}
+
+// ParseRequest parses the JSON API query parameters of r. See ParseQuery.
+func ParseRequest(r *http.Request) (*Query, []*ErrorObject) {
+	return ParseQuery(r.URL.Query())
+}
+
+// ParseQuery parses the include, fields, sort, filter and page query
+// parameters of a JSON API request.
+//
+// Malformed parameters, as well as implementation specific parameters that do
+// not follow the spec's naming rules (i.e. parameters consisting of only the
+// characters a-z), are returned as ErrorObjects with a 400 status; the query
+// is nil if there are any errors.
+//
+// See http://jsonapi.org/format/#query-parameters
+func ParseQuery(values url.Values) (*Query, []*ErrorObject) {
+	q := &Query{
+		Fields: map[string][]string{},
+		Filter: map[string][]string{},
+	}
+	var errs []*ErrorObject
+	page := map[string]string{}
+
+	params := make([]string, 0, len(values))
+	for param := range values {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	for _, param := range params {
+		family, member, ok := splitQueryParam(param)
+		if !ok {
+			errs = append(errs, newQueryError(param, "is not a valid query parameter name"))
+			continue
+		}
+
+		vals := values[param]
+		switch family {
+		case QueryParamInclude:
+			if member != "" {
+				errs = append(errs, newQueryError(param, "is not supported"))
+				continue
+			}
+			q.Include = []string{}
+			for _, path := range splitQueryValues(vals) {
+				if path == "" {
+					continue
+				}
+				if strings.Contains(path, "..") ||
+					strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") {
+					errs = append(errs, newQueryError(param, fmt.Sprintf("has an invalid path %q", path)))
+					continue
+				}
+				q.Include = append(q.Include, path)
+			}
+		case queryParamFields:
+			if member == "" {
+				errs = append(errs, newQueryError(param, "must name a resource type, e.g. fields[TYPE]"))
+				continue
+			}
+			fields := []string{}
+			for _, field := range splitQueryValues(vals) {
+				if field != "" {
+					fields = append(fields, field)
+				}
+			}
+			q.Fields[member] = fields
+		case QueryParamSort:
+			if member != "" {
+				errs = append(errs, newQueryError(param, "is not supported"))
+				continue
+			}
+			for _, field := range splitQueryValues(vals) {
+				desc := strings.HasPrefix(field, "-")
+				if desc {
+					field = field[1:]
+				}
+				if field == "" {
+					errs = append(errs, newQueryError(param, "has an empty sort field"))
+					continue
+				}
+				q.Sort = append(q.Sort, SortField{Field: field, Descending: desc})
+			}
+		case queryParamFilter:
+			q.Filter[member] = append(q.Filter[member], vals...)
+		case queryParamPage:
+			page[param] = vals[0]
+		default:
+			if isLowerAlpha(family) {
+				errs = append(errs, newQueryError(param, "is not supported"))
+			}
+		}
+	}
+
+	if len(page) > 0 {
+		p, pageErrs := parsePage(page)
+		errs = append(errs, pageErrs...)
+		q.Page = p
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return q, nil
+}
+
+// MarshalOptions returns the MarshalOptions that apply the include paths and
+// sparse fieldsets of q.
+func (q *Query) MarshalOptions() []MarshalOption {
+	var opts []MarshalOption
+	if q.Include != nil {
+		opts = append(opts, MarshalInclude(q.Include...))
+	}
+	if len(q.Fields) > 0 {
+		opts = append(opts, MarshalFields(q.Fields))
+	}
+	return opts
+}
+
+// Values returns the query parameters of q, as ParseQuery parses them, for a
+// request to a JSON API server.
+func (q *Query) Values() url.Values {
+	values := url.Values{}
+	if q == nil {
+		return values
+	}
+
+	if q.Include != nil {
+		values.Set(QueryParamInclude, strings.Join(q.Include, annotationSeperator))
+	}
+	for typ, fields := range q.Fields {
+		values.Set(fmt.Sprintf("%s[%s]", queryParamFields, typ), strings.Join(fields, annotationSeperator))
+	}
+	if len(q.Sort) > 0 {
+		fields := make([]string, len(q.Sort))
+		for i, field := range q.Sort {
+			fields[i] = field.String()
+		}
+		values.Set(QueryParamSort, strings.Join(fields, annotationSeperator))
+	}
+	for member, vals := range q.Filter {
+		param := queryParamFilter
+		if member != "" {
+			param = fmt.Sprintf("%s[%s]", queryParamFilter, member)
+		}
+		values[param] = append(values[param], vals...)
+	}
+
+	p := q.Page
+	switch p.Strategy {
+	case PageNumberPagination:
+		values.Set(QueryParamPageNumber, strconv.Itoa(p.Number))
+		if p.Size > 0 {
+			values.Set(QueryParamPageSize, strconv.Itoa(p.Size))
+		}
+	case OffsetPagination:
+		values.Set(QueryParamPageOffset, strconv.Itoa(p.Offset))
+		if p.Limit > 0 {
+			values.Set(QueryParamPageLimit, strconv.Itoa(p.Limit))
+		}
+	case CursorPagination:
+		values.Set(QueryParamPageCursor, p.Cursor)
+		if p.Size > 0 {
+			values.Set(QueryParamPageSize, strconv.Itoa(p.Size))
+		}
+	}
+
+	return values
+}
+
+func parsePage(page map[string]string) (Page, []*ErrorObject) {
+	var p Page
+	var errs []*ErrorObject
+
+	_, hasNumber := page[QueryParamPageNumber]
+	_, hasSize := page[QueryParamPageSize]
+	_, hasOffset := page[QueryParamPageOffset]
+	_, hasLimit := page[QueryParamPageLimit]
+	_, hasCursor := page[QueryParamPageCursor]
+
+	params := make([]string, 0, len(page))
+	for param := range page {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	for _, param := range params {
+		switch param {
+		case QueryParamPageNumber, QueryParamPageSize, QueryParamPageOffset,
+			QueryParamPageLimit, QueryParamPageCursor:
+		default:
+			errs = append(errs, newQueryError(param, "is not supported"))
+		}
+	}
+
+	switch {
+	case hasCursor:
+		p.Strategy = CursorPagination
+	case hasNumber || (hasSize && !hasOffset && !hasLimit):
+		p.Strategy = PageNumberPagination
+	case hasOffset || hasLimit:
+		p.Strategy = OffsetPagination
+	}
+
+	if (hasNumber && (hasOffset || hasLimit || hasCursor)) ||
+		((hasOffset || hasLimit) && (hasSize || hasCursor)) {
+		errs = append(errs, newQueryError(queryParamPage, "mixes more than one pagination strategy"))
+		return Page{}, errs
+	}
+
+	positive := func(param string, min int) int {
+		s, ok := page[param]
+		if !ok {
+			return 0
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil || v < min {
+			errs = append(errs, newQueryError(param, fmt.Sprintf("must be an integer of at least %d", min)))
+		}
+		return v
+	}
+
+	switch p.Strategy {
+	case PageNumberPagination:
+		p.Number = positive(QueryParamPageNumber, 1)
+		if !hasNumber {
+			p.Number = 1
+		}
+		p.Size = positive(QueryParamPageSize, 1)
+	case OffsetPagination:
+		p.Offset = positive(QueryParamPageOffset, 0)
+		p.Limit = positive(QueryParamPageLimit, 1)
+	case CursorPagination:
+		p.Cursor = page[QueryParamPageCursor]
+		p.Size = positive(QueryParamPageSize, 1)
+	}
+
+	if len(errs) > 0 {
+		return Page{}, errs
+	}
+	return p, nil
+}
+
+// splitQueryParam splits a query parameter name such as "fields[blogs]" into
+// its family, "fields", and member, "blogs". Nested members, such as
+// "filter[author][name]", are not valid.
+func splitQueryParam(param string) (family, member string, ok bool) {
+	i := strings.IndexByte(param, '[')
+	if i < 0 {
+		return param, "", param != "" && !strings.ContainsRune(param, ']')
+	}
+	if i == 0 || !strings.HasSuffix(param, "]") {
+		return "", "", false
+	}
+	member = param[i+1 : len(param)-1]
+	if strings.ContainsAny(member, "[]") {
+		return "", "", false
+	}
+	return param[:i], member, true
+}
+
+// splitQueryValues splits the comma-separated lists of values.
+func splitQueryValues(vals []string) []string {
+	var split []string
+	for _, v := range vals {
+		split = append(split, strings.Split(v, annotationSeperator)...)
+	}
+	return split
+}
+
+func isLowerAlpha(s string) bool {
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func newQueryError(param, detail string) *ErrorObject {
+	return &ErrorObject{
+		Title:  "Invalid query parameter",
+		Detail: fmt.Sprintf("The %s query parameter %s", param, detail),
+		Status: "400",
+		Source: &ErrorSource{Parameter: param},
+	}
+}
@@ -0,0 +1,169 @@
+package jsonapi
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	values, err := url.ParseQuery(
+		"include=posts.comments,current_post" +
+			"&fields[blogs]=title,posts&fields[posts]=" +
+			"&sort=-created_at,title" +
+			"&filter[author]=aren55555&filter[author]=shwoodard" +
+			"&page[number]=2&page[size]=25" +
+			"&camelCase=1",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, errs := ParseQuery(values)
+	if errs != nil {
+		t.Fatalf("Was not expecting errors, got %v", errs)
+	}
+
+	expected := &Query{
+		Include: []string{"posts.comments", "current_post"},
+		Fields: map[string][]string{
+			"blogs": {"title", "posts"},
+			"posts": {},
+		},
+		Sort: []SortField{
+			{Field: "created_at", Descending: true},
+			{Field: "title"},
+		},
+		Filter: map[string][]string{
+			"author": {"aren55555", "shwoodard"},
+		},
+		Page: Page{Strategy: PageNumberPagination, Number: 2, Size: 25},
+	}
+	if !reflect.DeepEqual(expected, q) {
+		t.Fatalf("Expected:\n%#v\nto equal:\n%#v", q, expected)
+	}
+}
+
+func TestQueryValues(t *testing.T) {
+	queries := []*Query{
+		{
+			Include: []string{"posts.comments", "current_post"},
+			Fields: map[string][]string{
+				"blogs": {"title", "posts"},
+				"posts": {},
+			},
+			Sort:   []SortField{{Field: "created_at", Descending: true}, {Field: "title"}},
+			Filter: map[string][]string{"author": {"aren55555", "shwoodard"}, "": {"all"}},
+			Page:   Page{Strategy: PageNumberPagination, Number: 2, Size: 25},
+		},
+		{
+			Include: []string{},
+			Fields:  map[string][]string{},
+			Filter:  map[string][]string{},
+			Page:    Page{Strategy: OffsetPagination, Offset: 20, Limit: 10},
+		},
+		{
+			Fields: map[string][]string{},
+			Filter: map[string][]string{},
+			Page:   Page{Strategy: CursorPagination, Cursor: "abc"},
+		},
+	}
+
+	for _, expected := range queries {
+		q, errs := ParseQuery(expected.Values())
+		if errs != nil {
+			t.Fatalf("Was not expecting errors, got %v", errs)
+		}
+		if !reflect.DeepEqual(expected, q) {
+			t.Fatalf("Expected:\n%#v\nto equal:\n%#v", q, expected)
+		}
+	}
+}
+
+func TestParseQuery_page(t *testing.T) {
+	scenarios := []struct {
+		query    string
+		expected Page
+	}{
+		{query: "", expected: Page{}},
+		{query: "page[size]=10", expected: Page{Strategy: PageNumberPagination, Number: 1, Size: 10}},
+		{query: "page[offset]=0&page[limit]=5", expected: Page{Strategy: OffsetPagination, Limit: 5}},
+		{query: "page[offset]=20", expected: Page{Strategy: OffsetPagination, Offset: 20}},
+		{query: "page[cursor]=abc&page[size]=5", expected: Page{Strategy: CursorPagination, Cursor: "abc", Size: 5}},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.query, func(t *testing.T) {
+			values, _ := url.ParseQuery(scenario.query)
+			q, errs := ParseQuery(values)
+			if errs != nil {
+				t.Fatalf("Was not expecting errors, got %v", errs)
+			}
+			if !reflect.DeepEqual(scenario.expected, q.Page) {
+				t.Fatalf("Expected:\n%#v\nto equal:\n%#v", q.Page, scenario.expected)
+			}
+		})
+	}
+}
+
+func TestParseQuery_errors(t *testing.T) {
+	scenarios := []struct {
+		query string
+		count int
+	}{
+		{query: "fields=title", count: 1},
+		{query: "include=posts..comments", count: 1},
+		{query: "sort=-", count: 1},
+		{query: "page[number]=0&page[size]=abc", count: 2},
+		{query: "page[number]=1&page[offset]=10", count: 1},
+		{query: "page[cursor]=abc&page[limit]=10", count: 1},
+		{query: "page[foo]=1", count: 1},
+		{query: "id=1", count: 1},
+		{query: "fields[blogs=title", count: 1},
+		{query: "filter[author][name]=aren55555", count: 1},
+		{query: "fields[blogs][posts]=title", count: 1},
+		{query: "page[cursor][after]=abc", count: 1},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.query, func(t *testing.T) {
+			values, _ := url.ParseQuery(scenario.query)
+			q, errs := ParseQuery(values)
+			if q != nil {
+				t.Fatal("Was expecting a nil query")
+			}
+			if e, a := scenario.count, len(errs); e != a {
+				t.Fatalf("Was expecting %d errors, got %d: %v", e, a, errs)
+			}
+			for _, err := range errs {
+				if e, a := "400", err.Status; e != a {
+					t.Fatalf("Was expecting status %q, got %q", e, a)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryMarshalOptions(t *testing.T) {
+	values, _ := url.ParseQuery("include=&fields[blogs]=title")
+	q, errs := ParseQuery(values)
+	if errs != nil {
+		t.Fatalf("Was not expecting errors, got %v", errs)
+	}
+
+	payload, err := Marshal(testBlog(), q.MarshalOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := payload.(*OnePayload)
+
+	if e, a := 0, len(one.Included); e != a {
+		t.Fatalf("Was expecting %d included resources, got %d", e, a)
+	}
+	if one.Data.Relationships != nil {
+		t.Fatal("Was expecting no relationships")
+	}
+	if e, a := 1, len(one.Data.Attributes); e != a {
+		t.Fatalf("Was expecting %d attribute, got %d", e, a)
+	}
+}
@@ -0,0 +1,187 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// TypeRegistry maps JSON API types to the struct types of the models that
+// represent them. It is used by UnmarshalTypes to unmarshal polymorphic
+// relationships, i.e. relation fields declared as an interface, and primary
+// data of mixed types. A TypeRegistry is safe for concurrent use.
+type TypeRegistry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// NewTypeRegistry returns an empty TypeRegistry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{types: map[string]reflect.Type{}}
+}
+
+// Register adds the struct types of models, which are struct pointers, under
+// the type of their "primary" annotation. It returns an error if a model has
+// no primary annotation, or if its type is already registered to a different
+// struct type; registering the same struct type again has no effect.
+func (r *TypeRegistry) Register(models ...interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, model := range models {
+		t := reflect.TypeOf(model)
+		if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+			return ErrUnexpectedType
+		}
+		t = t.Elem()
+
+		name := primaryTypeName(t)
+		if name == "" {
+			return fmt.Errorf("%v has no primary annotation", t)
+		}
+		if registered, ok := r.types[name]; ok && registered != t {
+			return fmt.Errorf("type %q is already registered to %v", name, registered)
+		}
+		r.types[name] = t
+	}
+	return nil
+}
+
+// lookup returns the struct type registered for the JSON API type name, or nil.
+func (r *TypeRegistry) lookup(name string) reflect.Type {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.types[name]
+}
+
+// defaultTypes holds the types added by Register.
+var defaultTypes = NewTypeRegistry()
+
+// Register adds the struct types of models to the registry used by
+// UnmarshalAny, and by UnmarshalPayload and UnmarshalManyPayload when no
+// UnmarshalTypes option is given. See TypeRegistry.Register.
+func Register(models ...interface{}) error {
+	return defaultTypes.Register(models...)
+}
+
+// AnyPayload holds the models of a document unmarshaled by UnmarshalAny, each
+// a pointer to the struct type registered for its resource type.
+type AnyPayload struct {
+	// Data holds the models of the primary data; it has at most one model if
+	// IsMany is false.
+	Data   []interface{}
+	IsMany bool
+	// Included holds the models of the "included" array.
+	Included []interface{}
+	Links    *Links
+	Meta     *Meta
+}
+
+// rawAnyPayload is decoded by UnmarshalAny; Data is decoded once it is known
+// whether it holds one resource or many.
+type rawAnyPayload struct {
+	Data     json.RawMessage `json:"data"`
+	Included []*rawNode      `json:"included"`
+	Links    *Links          `json:"links"`
+	Meta     *Meta           `json:"meta"`
+	Errors   ErrorObjects    `json:"errors"`
+}
+
+// UnmarshalAny unmarshals a document whose resource types are not known in
+// advance, such as one forwarded by a gateway. Each resource of "data" and
+// "included" is unmarshaled into a new instance of the struct type registered
+// for its type, with Register or the given UnmarshalTypes:
+//
+//	jsonapi.Register(new(Blog), new(Post), new(Comment))
+//
+//	doc, err := jsonapi.UnmarshalAny(r.Body)
+//	if err != nil {
+//		// ...
+//	}
+//	for _, model := range doc.Data {
+//		switch m := model.(type) {
+//		case *Blog:
+//			// ...
+//		}
+//	}
+//
+// A resource whose type is not registered results in an *UnmarshalError. If
+// the payload is an errors payload, its error objects are returned as
+// ErrorObjects.
+func UnmarshalAny(in io.Reader, opts ...UnmarshalOption) (*AnyPayload, error) {
+	o := newUnmarshalOptions(opts)
+
+	doc := new(rawAnyPayload)
+	if err := json.NewDecoder(in).Decode(doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Errors) > 0 {
+		return nil, doc.Errors
+	}
+
+	payload := &AnyPayload{Links: doc.Links, Meta: doc.Meta}
+
+	var data []*Node
+	if raw := bytes.TrimSpace(doc.Data); len(raw) > 0 && raw[0] == '[' {
+		payload.IsMany = true
+		var many []*rawNode
+		if err := json.Unmarshal(raw, &many); err != nil {
+			return nil, err
+		}
+		data = rawNodes(many)
+		setNodePointers(data, "/data")
+	} else if len(raw) > 0 {
+		one := new(rawNode)
+		if err := json.Unmarshal(raw, &one); err != nil {
+			return nil, err
+		}
+		if n := one.node(); n != nil {
+			n.pointer = "/data"
+			data = []*Node{n}
+		}
+	}
+
+	included := rawNodes(doc.Included)
+	setNodePointers(included, "/included")
+	includedMap := map[string]*Node{}
+	for _, n := range included {
+		if n == nil {
+			continue
+		}
+		includedMap[nodeKey(n)] = n
+	}
+
+	unmarshal := func(nodes []*Node, models *[]interface{}) error {
+		for _, n := range nodes {
+			if n == nil {
+				continue
+			}
+			model, err := o.newModel(anyModelType, n)
+			if err != nil {
+				return err
+			}
+			// included nodes are copied, as they may be related to again
+			if err := unmarshalResource(deepCopyNode(n), model, &includedMap, o); err != nil {
+				return err
+			}
+			*models = append(*models, model.Interface())
+		}
+		return nil
+	}
+
+	if err := unmarshal(data, &payload.Data); err != nil {
+		return nil, err
+	}
+	if err := unmarshal(included, &payload.Included); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// anyModelType is the empty interface type, which every model implements.
+var anyModelType = reflect.TypeOf((*interface{})(nil)).Elem()
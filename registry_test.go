@@ -0,0 +1,207 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testActorTypes(t *testing.T) *TypeRegistry {
+	types := NewTypeRegistry()
+	if err := types.Register(new(User), new(Bot), new(Activity)); err != nil {
+		t.Fatal(err)
+	}
+	return types
+}
+
+func TestTypeRegistry_Register(t *testing.T) {
+	types := testActorTypes(t)
+
+	// registering the same struct again is allowed
+	if err := types.Register(new(User)); err != nil {
+		t.Fatal(err)
+	}
+
+	type OtherUser struct {
+		ID int `jsonapi:"primary,users"`
+	}
+	if err := types.Register(new(OtherUser)); err == nil {
+		t.Fatal("Was expecting an error registering a second struct as users")
+	}
+	if err := types.Register(User{}); err != ErrUnexpectedType {
+		t.Fatalf("Was expecting ErrUnexpectedType for a struct, got %v", err)
+	}
+	if err := types.Register(new(Engine)); err == nil {
+		t.Fatal("Was expecting an error registering a struct without a primary annotation")
+	}
+
+	if e, a := reflect.TypeOf(Bot{}), types.lookup("bots"); e != a {
+		t.Fatalf("Was expecting bots to be registered to %v, got %v", e, a)
+	}
+}
+
+func TestPolymorphicRelationships(t *testing.T) {
+	activity := &Activity{
+		ID:    1,
+		Actor: &Bot{ID: 2, Version: "1.0"},
+		Participants: []Actor{
+			&User{ID: 3, Name: "Ann"},
+			&Bot{ID: 4, Version: "2.0"},
+		},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, activity, MarshalInclude("actor", "participants")); err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(Activity)
+	if err := UnmarshalPayload(buf, out, UnmarshalTypes(testActorTypes(t))); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(activity, out) {
+		t.Fatalf("Was expecting %#v, got %#v", activity, out)
+	}
+}
+
+func TestPolymorphicRelationships_nilPointer(t *testing.T) {
+	activity := &Activity{ID: 1, Actor: (*Bot)(nil)}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, activity); err != nil {
+		t.Fatal(err)
+	}
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	rels := out["data"].(map[string]interface{})["relationships"].(map[string]interface{})
+	actor, ok := rels["actor"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Was expecting an actor relationship, got %v", rels)
+	}
+	if data, ok := actor["data"]; !ok || data != nil {
+		t.Fatalf("Was expecting null actor data, got %v", actor)
+	}
+}
+
+func TestPolymorphicRelationships_notPointer(t *testing.T) {
+	for _, activity := range []*Activity{
+		{ID: 1, Actor: Guest{ID: 2}},
+		{ID: 1, Participants: []Actor{Guest{ID: 2}}},
+	} {
+		if err := MarshalPayload(bytes.NewBuffer(nil), activity); err != ErrUnexpectedType {
+			t.Fatalf("Was expecting ErrUnexpectedType, got %v", err)
+		}
+	}
+}
+
+func TestPolymorphicRelationships_unregisteredType(t *testing.T) {
+	in, err := payload(map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "activities",
+			"id":   "1",
+			"relationships": map[string]interface{}{
+				"participants": map[string]interface{}{
+					"data": []interface{}{
+						map[string]interface{}{"type": "users", "id": "3"},
+						map[string]interface{}{"type": "blogs", "id": "4"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = UnmarshalPayload(bytes.NewReader(in), new(Activity), UnmarshalTypes(testActorTypes(t)), UnmarshalErrorPointers())
+	uErr, ok := err.(*UnmarshalError)
+	if !ok {
+		t.Fatalf("Was expecting an *UnmarshalError, got %#v", err)
+	}
+	if e, a := "/data/relationships/participants/data/1/type", uErr.Pointer; e != a {
+		t.Fatalf("Was expecting the pointer %q, got %q", e, a)
+	}
+}
+
+func TestUnmarshalManyPayload_mixedTypes(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, []Actor{&User{ID: 1, Name: "Ann"}, &Bot{ID: 2, Version: "1.0"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	actors, err := UnmarshalManyPayload(buf, reflect.TypeOf((*Actor)(nil)).Elem(), UnmarshalTypes(testActorTypes(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{&User{ID: 1, Name: "Ann"}, &Bot{ID: 2, Version: "1.0"}}
+	if !reflect.DeepEqual(expected, actors) {
+		t.Fatalf("Was expecting %#v, got %#v", expected, actors)
+	}
+}
+
+func TestUnmarshalAny(t *testing.T) {
+	if err := Register(new(User), new(Bot), new(Activity)); err != nil {
+		t.Fatal(err)
+	}
+
+	activity := &Activity{
+		ID:           1,
+		Actor:        &Bot{ID: 2, Version: "1.0"},
+		Participants: []Actor{&User{ID: 3, Name: "Ann"}},
+	}
+
+	scenarios := []struct {
+		name   string
+		models interface{}
+		isMany bool
+	}{
+		{name: "one", models: activity},
+		{name: "many", models: []interface{}{activity, &User{ID: 4, Name: "Bob"}}, isMany: true},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			if err := MarshalPayload(buf, scenario.models); err != nil {
+				t.Fatal(err)
+			}
+
+			doc, err := UnmarshalAny(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if doc.IsMany != scenario.isMany {
+				t.Fatalf("Was expecting IsMany to be %v", scenario.isMany)
+			}
+			if !reflect.DeepEqual(activity, doc.Data[0]) {
+				t.Fatalf("Was expecting %#v, got %#v", activity, doc.Data[0])
+			}
+			if scenario.isMany && !reflect.DeepEqual(&User{ID: 4, Name: "Bob"}, doc.Data[1]) {
+				t.Fatalf("Was expecting user 4, got %#v", doc.Data[1])
+			}
+			expectedIncluded := []interface{}{&Bot{ID: 2, Version: "1.0"}, &User{ID: 3, Name: "Ann"}}
+			if !reflect.DeepEqual(expectedIncluded, doc.Included) {
+				t.Fatalf("Was expecting the included models %#v, got %#v", expectedIncluded, doc.Included)
+			}
+		})
+	}
+}
+
+func TestUnmarshalAny_unregisteredType(t *testing.T) {
+	in := []byte(`{"data": {"type": "unregistered", "id": "1"}}`)
+
+	_, err := UnmarshalAny(bytes.NewReader(in))
+	uErr, ok := err.(*UnmarshalError)
+	if !ok {
+		t.Fatalf("Was expecting an *UnmarshalError, got %#v", err)
+	}
+	if e, a := "/data/type", uErr.Pointer; e != a {
+		t.Fatalf("Was expecting the pointer %q, got %q", e, a)
+	}
+}
@@ -0,0 +1,180 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// MarshalRelationship writes the relationship name of model, a struct
+// pointer, as a relationship document: its resource linkage along with the
+// links and meta given by the model's JSONAPIRelationshipLinks and
+// JSONAPIRelationshipMeta methods, e.g.
+//
+//	{"data": [{"type": "posts", "id": "1"}], "links": {...}}
+//
+// This is the response of relationship endpoints such as
+// /blogs/1/relationships/posts.
+//
+// See http://jsonapi.org/format/#fetching-relationships
+func MarshalRelationship(w io.Writer, model interface{}, name string) error {
+	vals := reflect.ValueOf(model)
+	if vals.Kind() != reflect.Ptr || reflect.Indirect(vals).Kind() != reflect.Struct {
+		return ErrUnexpectedType
+	}
+	modelType := vals.Type().Elem()
+
+	path, ok := schemaOf(modelType).rels[name]
+	if !ok {
+		return fmt.Errorf("%q is not a relationship of %q", name, primaryTypeName(modelType))
+	}
+
+	// only the relationship is visited, and nothing is sideloaded
+	node, err := visitModelFields(model, nil, true, nil, includeTree{}, fieldset{name: true})
+	if err != nil {
+		return err
+	}
+
+	relationship, ok := node.Relationships[name]
+	if !ok {
+		// omitted as empty, or within a nil embedded struct
+		if fieldTypeByPath(modelType, path).Kind() == reflect.Slice {
+			relationship = &RelationshipManyNode{Data: []*Node{}}
+		} else {
+			relationship = &RelationshipOneNode{}
+		}
+	}
+
+	// resources leave out the links and meta of null to-one relationships,
+	// but the relationship document keeps them
+	if one, ok := relationship.(*RelationshipOneNode); ok && one.Data == nil {
+		if linkableModel, ok := model.(RelationshipLinkable); ok {
+			one.Links = linkableModel.JSONAPIRelationshipLinks(name)
+		}
+		if metableModel, ok := model.(RelationshipMetable); ok {
+			one.Meta = metableModel.JSONAPIRelationshipMeta(name)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(relationship)
+}
+
+// UnmarshalRelationship reads a relationship document, as sent to update a
+// relationship endpoint, into the relationship name of model, a struct
+// pointer. The related models are stubs with only their primary and client-id
+// fields set. A null to-one linkage sets the field to nil, clearing the
+// relationship:
+//
+//	blog := &Blog{ID: id}
+//	if err := jsonapi.UnmarshalRelationship(r.Body, blog, "posts"); err != nil {
+//		// ...
+//	}
+//	// blog.Posts holds a *Post for each posted identifier
+//
+// The UnmarshalOptions apply as they do to UnmarshalPayload; with
+// UnmarshalStrict, linkage of the wrong type is rejected.
+//
+// See http://jsonapi.org/format/#crud-updating-relationships
+func UnmarshalRelationship(in io.Reader, model interface{}, name string, opts ...UnmarshalOption) error {
+	vals := reflect.ValueOf(model)
+	if vals.Kind() != reflect.Ptr || reflect.Indirect(vals).Kind() != reflect.Struct {
+		return ErrUnexpectedType
+	}
+	modelType := vals.Type().Elem()
+
+	path, ok := schemaOf(modelType).rels[name]
+	if !ok {
+		return fmt.Errorf("%q is not a relationship of %q", name, primaryTypeName(modelType))
+	}
+
+	o := newUnmarshalOptions(opts)
+	raw, _, err := decodeRelationshipDocument(in)
+	if err != nil {
+		return o.unmarshalError(err)
+	}
+
+	fieldValue := fieldByPath(vals.Elem(), path, true)
+	handler := handleToOneRelationUnmarshal
+	if fieldValue.Kind() == reflect.Slice {
+		handler = handleToManyRelationUnmarshal
+	}
+
+	v, err := handler(raw, fieldValue.Type(), nil, "", o)
+	if err != nil {
+		return o.unmarshalError(err)
+	}
+	if v == nil {
+		fieldValue.Set(reflect.Zero(fieldValue.Type()))
+	} else {
+		fieldValue.Set(*v)
+	}
+	return nil
+}
+
+// Linkage holds the resource identifiers of a relationship document, as read
+// by UnmarshalLinkage.
+type Linkage struct {
+	// Data holds the resource identifiers, which only have their type, id
+	// and meta set; it has at most one identifier if IsMany is false.
+	Data   []*Node
+	IsMany bool
+}
+
+// UnmarshalLinkage reads the resource identifiers of a relationship document,
+// e.g. to remove the identified resources from a to-many relationship without
+// unmarshaling them into models.
+func UnmarshalLinkage(in io.Reader) (*Linkage, error) {
+	_, doc, err := decodeRelationshipDocument(in)
+	if err != nil {
+		return nil, err
+	}
+
+	linkage := new(Linkage)
+	if raw := doc["data"]; len(raw) > 0 && raw[0] == '[' {
+		linkage.IsMany = true
+		err = json.Unmarshal(raw, &linkage.Data)
+	} else {
+		var n *Node
+		if err = json.Unmarshal(raw, &n); err == nil && n != nil {
+			linkage.Data = []*Node{n}
+		}
+	}
+	if err != nil {
+		return nil, &UnmarshalError{Pointer: "/data", Err: err}
+	}
+	return linkage, nil
+}
+
+// decodeRelationshipDocument reads a relationship document, which must have a
+// "data" member, and returns it both raw and decoded into its members. If the
+// payload is an errors payload, its error objects are returned as
+// ErrorObjects.
+func decodeRelationshipDocument(in io.Reader) (json.RawMessage, map[string]json.RawMessage, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(in).Decode(&raw); err != nil {
+		return nil, nil, err
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	if errsRaw, ok := doc["errors"]; ok {
+		var errs ErrorObjects
+		if err := json.Unmarshal(errsRaw, &errs); err != nil {
+			return nil, nil, err
+		}
+		if len(errs) > 0 {
+			return nil, nil, errs
+		}
+	}
+	if _, ok := doc["data"]; !ok {
+		return nil, nil, &UnmarshalError{
+			Pointer: "/data",
+			Err:     errors.New("a relationship document must have a data member"),
+		}
+	}
+	return raw, doc, nil
+}
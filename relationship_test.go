@@ -0,0 +1,165 @@
+package jsonapi
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalRelationship(t *testing.T) {
+	blog := testBlog()
+	blog.CurrentPost = nil
+
+	scenarios := []struct {
+		name     string
+		relation string
+		expected string
+	}{
+		{
+			name:     "to-many",
+			relation: "posts",
+			expected: `{
+				"data": [{"type": "posts", "id": "1"}, {"type": "posts", "id": "2"}],
+				"links": {"related": {"href": "https://example.com/api/blogs/5/posts", "meta": {"count": 2}}},
+				"meta": {"this": {"can": {"go": ["as", "deep", {"as": "required"}]}}}
+			}`,
+		},
+		{
+			name:     "null to-one",
+			relation: "current_post",
+			expected: `{
+				"data": null,
+				"links": {
+					"self": "https://example.com/api/posts/3",
+					"related": {"href": "https://example.com/api/blogs/5/current_post"}
+				},
+				"meta": {"detail": "extra current_post detail"}
+			}`,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			if err := MarshalRelationship(out, blog, scenario.relation); err != nil {
+				t.Fatal(err)
+			}
+
+			equal, err := isJSONEqual([]byte(scenario.expected), out.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equal {
+				t.Fatalf("Was expecting %s, got %s", scenario.expected, out.Bytes())
+			}
+		})
+	}
+}
+
+func TestMarshalRelationship_unknownRelationship(t *testing.T) {
+	if err := MarshalRelationship(bytes.NewBuffer(nil), testBlog(), "authors"); err == nil {
+		t.Fatal("Was expecting an error for an unknown relationship")
+	}
+}
+
+func TestUnmarshalRelationship(t *testing.T) {
+	blog := &Blog{ID: 5, CurrentPost: &Post{ID: 1}}
+
+	if err := UnmarshalRelationship(strings.NewReader(`{"data": [{"type": "posts", "id": "1"}, {"type": "posts", "id": "2"}]}`), blog, "posts"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []*Post{{ID: 1}, {ID: 2}}
+	if !reflect.DeepEqual(expected, blog.Posts) {
+		t.Fatalf("Was expecting %#v, got %#v", expected, blog.Posts)
+	}
+
+	if err := UnmarshalRelationship(strings.NewReader(`{"data": null}`), blog, "current_post"); err != nil {
+		t.Fatal(err)
+	}
+	if blog.CurrentPost != nil {
+		t.Fatalf("Was expecting the current post to be cleared, got %#v", blog.CurrentPost)
+	}
+}
+
+func TestUnmarshalRelationship_errors(t *testing.T) {
+	scenarios := []struct {
+		name    string
+		in      string
+		opts    []UnmarshalOption
+		pointer string
+	}{
+		{
+			name:    "missing data",
+			in:      `{"meta": {}}`,
+			opts:    []UnmarshalOption{UnmarshalErrorPointers()},
+			pointer: "/data",
+		},
+		{
+			name:    "wrong type",
+			in:      `{"data": [{"type": "comments", "id": "1"}]}`,
+			opts:    []UnmarshalOption{UnmarshalErrorPointers()},
+			pointer: "/data/0/type",
+		},
+		{
+			name:    "invalid id",
+			in:      `{"data": [{"type": "posts", "id": "a"}]}`,
+			opts:    []UnmarshalOption{UnmarshalErrorPointers()},
+			pointer: "/data/0/id",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			err := UnmarshalRelationship(strings.NewReader(scenario.in), new(Blog), "posts", scenario.opts...)
+			uErr, ok := err.(*UnmarshalError)
+			if !ok {
+				t.Fatalf("Was expecting an *UnmarshalError, got %#v", err)
+			}
+			if e, a := scenario.pointer, uErr.Pointer; e != a {
+				t.Fatalf("Was expecting the pointer %q, got %q", e, a)
+			}
+		})
+	}
+}
+
+func TestUnmarshalRelationship_errorsWithoutPointers(t *testing.T) {
+	err := UnmarshalRelationship(strings.NewReader(`{"data": [{"type": "posts", "id": "a"}]}`), new(Blog), "posts")
+	if err != ErrBadJSONAPIID {
+		t.Fatalf("Was expecting ErrBadJSONAPIID, got %#v", err)
+	}
+
+	err = UnmarshalRelationship(strings.NewReader(`{"meta": {}}`), new(Blog), "posts")
+	if _, ok := err.(*UnmarshalError); ok || err == nil {
+		t.Fatalf("Was expecting the underlying error, got %#v", err)
+	}
+}
+
+func TestUnmarshalLinkage(t *testing.T) {
+	scenarios := []struct {
+		in     string
+		isMany bool
+		ids    []string
+	}{
+		{in: `{"data": [{"type": "posts", "id": "1"}, {"type": "posts", "id": "2"}]}`, isMany: true, ids: []string{"1", "2"}},
+		{in: `{"data": {"type": "posts", "id": "3"}}`, ids: []string{"3"}},
+		{in: `{"data": null}`, ids: []string{}},
+	}
+
+	for _, scenario := range scenarios {
+		linkage, err := UnmarshalLinkage(strings.NewReader(scenario.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if linkage.IsMany != scenario.isMany {
+			t.Fatalf("Was expecting IsMany to be %v for %s", scenario.isMany, scenario.in)
+		}
+		ids := []string{}
+		for _, n := range linkage.Data {
+			ids = append(ids, n.ID)
+		}
+		if !reflect.DeepEqual(scenario.ids, ids) {
+			t.Fatalf("Was expecting the ids %v, got %v", scenario.ids, ids)
+		}
+	}
+}
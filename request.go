@@ -1,12 +1,12 @@
 package jsonapi
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -37,6 +37,209 @@ var (
 	timeType    = ptrTimeType.Elem()
 )
 
+// UnmarshalOption is used to configure the optional behavior of
+// UnmarshalPayload and UnmarshalManyPayload.
+type UnmarshalOption func(*unmarshalOptions)
+
+// unmarshalOptions holds the settings collected from the given
+// UnmarshalOptions.
+type unmarshalOptions struct {
+	// strict rejects unknown members and mismatched relationship types.
+	strict bool
+	// presence, if set, records the members present in the primary data.
+	presence *Presence
+	// types resolves the models of polymorphic relationships and primary data.
+	types *TypeRegistry
+	// pointers returns *UnmarshalErrors rather than the errors they wrap.
+	pointers bool
+}
+
+func newUnmarshalOptions(opts []UnmarshalOption) *unmarshalOptions {
+	o := &unmarshalOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// UnmarshalStrict rejects payloads with attributes or relationships that are
+// not declared by the model, e.g. a misspelled "titel" attribute, which are
+// otherwise ignored. It also rejects resource linkage whose type does not
+// match the type of the relationship's model.
+//
+// The offending members are returned as ErrorObjects with a 400 status and a
+// source pointer.
+func UnmarshalStrict() UnmarshalOption {
+	return func(o *unmarshalOptions) {
+		o.strict = true
+	}
+}
+
+// UnmarshalErrorPointers returns members of the payload that cannot be
+// unmarshaled into the model as *UnmarshalErrors, which carry the JSON Pointer
+// to the invalid member and convert to an ErrorObject:
+//
+//	err := jsonapi.UnmarshalPayload(r.Body, blog, jsonapi.UnmarshalErrorPointers())
+//	if uErr, ok := err.(*jsonapi.UnmarshalError); ok {
+//		jsonapi.WriteErrors(w, []*jsonapi.ErrorObject{uErr.ErrorObject()})
+//	}
+//
+// Without it, the underlying error is returned as it is, e.g. ErrBadJSONAPIID
+// or a *json.UnmarshalTypeError.
+func UnmarshalErrorPointers() UnmarshalOption {
+	return func(o *unmarshalOptions) {
+		o.pointers = true
+	}
+}
+
+// unmarshalError returns err, or the error wrapped by an *UnmarshalError
+// unless UnmarshalErrorPointers was given.
+func (o *unmarshalOptions) unmarshalError(err error) error {
+	if uErr, ok := err.(*UnmarshalError); ok && !o.pointers {
+		return uErr.Err
+	}
+	return err
+}
+
+// UnmarshalTypes resolves the models of relationships declared as an
+// interface, and of the primary data of UnmarshalManyPayload when it is given
+// an interface type, from the types registered with r:
+//
+//	type Author interface{}
+//
+//	type Comment struct {
+//		ID     int    `jsonapi:"primary,comments"`
+//		Author Author `jsonapi:"relation,author"`
+//	}
+//
+//	types := jsonapi.NewTypeRegistry()
+//	types.Register(new(User), new(Bot))
+//	jsonapi.UnmarshalPayload(r.Body, comment, jsonapi.UnmarshalTypes(types))
+//
+// A resource whose type is not registered, or whose model does not implement
+// the interface, results in an *UnmarshalError. Without UnmarshalTypes, the
+// types added by Register are used.
+func UnmarshalTypes(r *TypeRegistry) UnmarshalOption {
+	return func(o *unmarshalOptions) {
+		o.types = r
+	}
+}
+
+// registry returns the TypeRegistry given by UnmarshalTypes, or the one
+// Register adds to.
+func (o *unmarshalOptions) registry() *TypeRegistry {
+	if o.types != nil {
+		return o.types
+	}
+	return defaultTypes
+}
+
+// newModel returns a new model for the node n. t is either a pointer to the
+// model's struct type, or an interface type, in which case the model is of the
+// type registered for n.Type.
+func (o *unmarshalOptions) newModel(t reflect.Type, n *Node) (reflect.Value, error) {
+	if t.Kind() != reflect.Interface {
+		return reflect.New(t.Elem()), nil
+	}
+
+	modelType := o.registry().lookup(n.Type)
+	if modelType == nil || !reflect.PtrTo(modelType).Implements(t) {
+		return reflect.Value{}, &UnmarshalError{
+			Pointer: n.pointer + "/type",
+			Err:     fmt.Errorf("%q is not a registered type implementing %v", n.Type, t),
+		}
+	}
+	return reflect.New(modelType), nil
+}
+
+// Presence records which attributes and relationships of a resource were
+// present in an unmarshaled payload, including those set to null.
+type Presence struct {
+	// Attributes maps the name of each present attribute to the path of the
+	// struct field it was unmarshaled into, e.g. "Engine.HorsePower" for a
+	// field of an embedded struct.
+	Attributes map[string]string
+	// Relationships maps the name of each present relationship to the path of
+	// the struct field it was unmarshaled into.
+	Relationships map[string]string
+}
+
+// HasAttribute reports whether the attribute name was present.
+func (p *Presence) HasAttribute(name string) bool {
+	_, ok := p.Attributes[name]
+	return ok
+}
+
+// HasRelationship reports whether the relationship name was present.
+func (p *Presence) HasRelationship(name string) bool {
+	_, ok := p.Relationships[name]
+	return ok
+}
+
+// FieldPaths returns the sorted struct field paths of all present attributes
+// and relationships.
+func (p *Presence) FieldPaths() []string {
+	paths := make([]string, 0, len(p.Attributes)+len(p.Relationships))
+	for _, path := range p.Attributes {
+		paths = append(paths, path)
+	}
+	for _, path := range p.Relationships {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// record adds the members of data that are declared by the struct type t.
+func (p *Presence) record(data *Node, t reflect.Type) {
+	if data == nil {
+		return
+	}
+
+	schema := schemaOf(t)
+
+	for name := range data.Attributes {
+		if path, ok := schema.attrs[name]; ok {
+			p.Attributes[name] = path.name
+		}
+	}
+	for name := range data.Relationships {
+		if path, ok := schema.rels[name]; ok {
+			p.Relationships[name] = path.name
+		}
+	}
+}
+
+// UnmarshalPartialPayload does the same as UnmarshalPayload, and also returns
+// the attributes and relationships that were present in the payload. This is
+// useful for PATCH requests, where absent members must be left unchanged
+// rather than be set to their zero values:
+//
+//	blog := new(Blog)
+//	presence, err := jsonapi.UnmarshalPartialPayload(r.Body, blog)
+//	if err != nil {
+//		// ...
+//	}
+//	if presence.HasAttribute("title") {
+//		// ...update the title column...
+//	}
+//
+// See http://jsonapi.org/format/#crud-updating
+func UnmarshalPartialPayload(in io.Reader, model interface{}, opts ...UnmarshalOption) (*Presence, error) {
+	presence := &Presence{
+		Attributes:    map[string]string{},
+		Relationships: map[string]string{},
+	}
+	opts = append(opts, func(o *unmarshalOptions) {
+		o.presence = presence
+	})
+
+	if err := UnmarshalPayload(in, model, opts...); err != nil {
+		return nil, err
+	}
+	return presence, nil
+}
+
 // UnmarshalPayload converts an io into a struct instance using jsonapi tags on
 // struct fields. This method supports single request payloads only, at the
 // moment. Bulk creates and updates are not supported yet.
@@ -69,55 +272,198 @@ var (
 //
 // Visit https://github.com/google/jsonapi#create for more info.
 //
+// With UnmarshalErrorPointers, members of the payload that cannot be
+// unmarshaled into the model result in an *UnmarshalError, which carries the
+// JSON Pointer to the invalid member. If the payload is an errors payload, its
+// error objects are returned as ErrorObjects.
+//
 // model interface{} should be a pointer to a struct.
-func UnmarshalPayload(in io.Reader, model interface{}) error {
-	payload := new(OnePayload)
+func UnmarshalPayload(in io.Reader, model interface{}, opts ...UnmarshalOption) error {
+	o := newUnmarshalOptions(opts)
+	return o.unmarshalError(unmarshalOne(in, model, o))
+}
 
-	if err := json.NewDecoder(in).Decode(payload); err != nil {
+// unmarshalOne does the work of UnmarshalPayload.
+func unmarshalOne(in io.Reader, model interface{}, o *unmarshalOptions) error {
+	doc := new(rawOnePayload)
+	if err := json.NewDecoder(in).Decode(doc); err != nil {
 		return err
 	}
+	if len(doc.Errors) > 0 {
+		return doc.Errors
+	}
+	payload := &OnePayload{Data: doc.Data.node(), Included: rawNodes(doc.Included)}
+
+	if payload.Data != nil {
+		payload.Data.pointer = "/data"
+	}
+	setNodePointers(payload.Included, "/included")
+
+	if o.presence != nil {
+		o.presence.record(payload.Data, reflect.TypeOf(model).Elem())
+	}
 
 	if payload.Included != nil {
 		includedMap := make(map[string]*Node)
 		for _, included := range payload.Included {
-			key := fmt.Sprintf("%s,%s", included.Type, included.ID)
-			includedMap[key] = included
+			includedMap[nodeKey(included)] = included
 		}
 
-		return unmarshalNode(payload.Data, reflect.ValueOf(model), &includedMap)
+		return unmarshalResource(payload.Data, reflect.ValueOf(model), &includedMap, o)
 	}
-	return unmarshalNode(payload.Data, reflect.ValueOf(model), nil)
+	return unmarshalResource(payload.Data, reflect.ValueOf(model), nil, o)
 }
 
 // UnmarshalManyPayload converts an io into a set of struct instances using
-// jsonapi tags on the type's struct fields.
-func UnmarshalManyPayload(in io.Reader, t reflect.Type) ([]interface{}, error) {
-	payload := new(ManyPayload)
-
-	if err := json.NewDecoder(in).Decode(payload); err != nil {
+// jsonapi tags on the type's struct fields. If the payload is an errors
+// payload, its error objects are returned as ErrorObjects; see
+// UnmarshalPayload for the other errors.
+//
+// t is a pointer to a struct type, or, to unmarshal resources of mixed types,
+// an interface type with the models given by UnmarshalTypes, e.g.
+//
+//	jsonapi.UnmarshalManyPayload(r.Body, reflect.TypeOf((*FeedItem)(nil)).Elem(), jsonapi.UnmarshalTypes(types))
+func UnmarshalManyPayload(in io.Reader, t reflect.Type, opts ...UnmarshalOption) ([]interface{}, error) {
+	models := []interface{}{} // will be populated from the "data"
+	if err := unmarshalMany(in, t, newUnmarshalOptions(opts), func(model reflect.Value) {
+		models = append(models, model.Interface())
+	}); err != nil {
 		return nil, err
 	}
+	return models, nil
+}
+
+// unmarshalMany does the work of UnmarshalManyPayload, calling add with each
+// model, a pointer of type t, in the order of "data".
+func unmarshalMany(in io.Reader, t reflect.Type, o *unmarshalOptions, add func(model reflect.Value)) error {
+	doc := new(rawManyPayload)
+	if err := json.NewDecoder(in).Decode(doc); err != nil {
+		return err
+	}
+	if len(doc.Errors) > 0 {
+		return doc.Errors
+	}
+	payload := &ManyPayload{Data: rawNodes(doc.Data), Included: rawNodes(doc.Included)}
+
+	setNodePointers(payload.Data, "/data")
+	setNodePointers(payload.Included, "/included")
 
-	models := []interface{}{}         // will be populated from the "data"
 	includedMap := map[string]*Node{} // will be populate from the "included"
 
 	if payload.Included != nil {
 		for _, included := range payload.Included {
-			key := fmt.Sprintf("%s,%s", included.Type, included.ID)
-			includedMap[key] = included
+			includedMap[nodeKey(included)] = included
 		}
 	}
 
 	for _, data := range payload.Data {
-		model := reflect.New(t.Elem())
-		err := unmarshalNode(data, model, &includedMap)
+		model, err := o.newModel(t, data)
 		if err != nil {
-			return nil, err
+			return o.unmarshalError(err)
 		}
-		models = append(models, model.Interface())
+		if err := unmarshalResource(data, model, &includedMap, o); err != nil {
+			return o.unmarshalError(err)
+		}
+		add(model)
 	}
 
-	return models, nil
+	return nil
+}
+
+// rawOnePayload and rawManyPayload are decoded in place of OnePayload and
+// ManyPayload when unmarshaling, along with the errors of an errors payload.
+type rawOnePayload struct {
+	Data     *rawNode     `json:"data"`
+	Included []*rawNode   `json:"included"`
+	Errors   ErrorObjects `json:"errors"`
+}
+
+type rawManyPayload struct {
+	Data     []*rawNode   `json:"data"`
+	Included []*rawNode   `json:"included"`
+	Errors   ErrorObjects `json:"errors"`
+}
+
+// rawNode is a Node whose attribute and relationship values are left as raw
+// JSON, so that each value is decoded only once, directly into its field.
+type rawNode struct {
+	Type          string                     `json:"type"`
+	ID            string                     `json:"id"`
+	ClientID      string                     `json:"client-id"`
+	Lid           string                     `json:"lid"`
+	Attributes    map[string]json.RawMessage `json:"attributes"`
+	Relationships map[string]json.RawMessage `json:"relationships"`
+	Links         *Links                     `json:"links"`
+	Meta          *Meta                      `json:"meta"`
+}
+
+// node returns the Node of r; the values of its attributes and relationships
+// are json.RawMessages.
+func (r *rawNode) node() *Node {
+	if r == nil {
+		return nil
+	}
+
+	n := &Node{
+		Type:     r.Type,
+		ID:       r.ID,
+		ClientID: r.ClientID,
+		Lid:      r.Lid,
+		Links:    r.Links,
+		Meta:     r.Meta,
+	}
+	if r.Attributes != nil {
+		n.Attributes = make(attributes, len(r.Attributes))
+		for k, v := range r.Attributes {
+			n.Attributes[k] = v
+		}
+	}
+	if r.Relationships != nil {
+		n.Relationships = make(map[string]interface{}, len(r.Relationships))
+		for k, v := range r.Relationships {
+			n.Relationships[k] = v
+		}
+	}
+	return n
+}
+
+func rawNodes(raws []*rawNode) []*Node {
+	if raws == nil {
+		return nil
+	}
+	nodes := make([]*Node, len(raws))
+	for i, r := range raws {
+		nodes[i] = r.node()
+	}
+	return nodes
+}
+
+// rawValue returns the JSON of an attribute or relationship value; values
+// decoded from a payload are already json.RawMessages.
+func rawValue(v interface{}) ([]byte, error) {
+	if raw, ok := v.(json.RawMessage); ok {
+		return raw, nil
+	}
+	return json.Marshal(v)
+}
+
+// isNull reports whether an attribute or relationship value is absent or null.
+func isNull(v interface{}) bool {
+	if raw, ok := v.(json.RawMessage); ok {
+		return string(raw) == "null"
+	}
+	return v == nil
+}
+
+// unmarshalResource unmarshals the resource data into model; in strict mode
+// unknown members are rejected before anything is unmarshaled.
+func unmarshalResource(data *Node, model reflect.Value, included *map[string]*Node, opts *unmarshalOptions) error {
+	if opts.strict {
+		if errs := unknownMembers(data, model.Type().Elem()); len(errs) > 0 {
+			return errs
+		}
+	}
+	return unmarshalNode(data, model, included, opts)
 }
 
 // unmarshalNode handles embedded struct models from top to down.
@@ -125,70 +471,33 @@ func UnmarshalManyPayload(in io.Reader, t reflect.Type) ([]interface{}, error) {
 // the handling the embedded structs are done last, so that you get the expected composition behavior
 // data (*Node) attributes are cleared on each success.
 // relations/sideloaded models use deeply copied Nodes (since those sideloaded models can be referenced in multiple relations)
-func unmarshalNode(data *Node, model reflect.Value, included *map[string]*Node) (err error) {
+func unmarshalNode(data *Node, model reflect.Value, included *map[string]*Node, opts *unmarshalOptions) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("data is not a jsonapi representation of '%v'", model.Type())
+			err = &UnmarshalError{
+				Pointer: nodePointer(data),
+				Err:     fmt.Errorf("data is not a jsonapi representation of '%v'", model.Type()),
+			}
 		}
 	}()
 
 	modelValue := model.Elem()
-	modelType := model.Type().Elem()
-
-	type embedded struct {
-		structField, model reflect.Value
-	}
-	embeddeds := []*embedded{}
-
-	for i := 0; i < modelValue.NumField(); i++ {
-		structField := modelType.Field(i)
-		fieldValue := modelValue.Field(i)
-		tag := structField.Tag.Get(annotationJSONAPI)
+	schema := schemaOf(modelValue.Type())
 
-		// handle explicit ignore annotation
-		if shouldIgnoreField(tag) {
-			continue
-		}
-
-		// handles embedded structs
-		if isEmbeddedStruct(structField) {
-			embeddeds = append(embeddeds,
-				&embedded{
-					model:       reflect.ValueOf(fieldValue.Addr().Interface()),
-					structField: fieldValue,
-				},
-			)
-			continue
-		}
+	for _, f := range schema.fields {
+		structField := f.field
+		fieldValue := modelValue.Field(f.index)
+		args := f.args
 
-		// handles pointers to embedded structs
-		if isEmbeddedStructPtr(structField) {
-			embeddeds = append(embeddeds,
-				&embedded{
-					model:       reflect.ValueOf(fieldValue.Interface()),
-					structField: fieldValue,
-				},
-			)
-			continue
-		}
-
-		// handle tagless; after handling embedded structs (which could be tagless)
-		if tag == "" {
-			continue
-		}
-
-		args := strings.Split(tag, annotationSeperator)
-		// require atleast 1
-		if len(args) < 1 {
-			return ErrBadJSONAPIStructTag
-		}
-
-		// args[0] == annotation
-		switch args[0] {
+		switch f.annotation {
 		case annotationClientID:
 			if err := handleClientIDUnmarshal(data, args, fieldValue); err != nil {
 				return err
 			}
+		case annotationLid:
+			if err := handleLidUnmarshal(data, args, fieldValue); err != nil {
+				return err
+			}
 		case annotationPrimary:
 			if err := handlePrimaryUnmarshal(data, args, structField, fieldValue); err != nil {
 				return err
@@ -198,7 +507,7 @@ func unmarshalNode(data *Node, model reflect.Value, included *map[string]*Node)
 				return err
 			}
 		case annotationRelation:
-			if err := handleRelationUnmarshal(data, args, fieldValue, included); err != nil {
+			if err := handleRelationUnmarshal(data, args, fieldValue, included, opts); err != nil {
 				return err
 			}
 		default:
@@ -207,23 +516,29 @@ func unmarshalNode(data *Node, model reflect.Value, included *map[string]*Node)
 	}
 
 	// handle embedded last
-	for _, em := range embeddeds {
+	for _, em := range schema.embeddeds {
+		fieldValue := modelValue.Field(em.index)
+
 		// if nil, need to construct and rollback accordingly
-		if em.model.IsNil() {
+		if em.ptr && fieldValue.IsNil() {
 			copy := deepCopyNode(data)
-			tmp := reflect.New(em.model.Type().Elem())
-			if err := unmarshalNode(copy, tmp, included); err != nil {
+			tmp := reflect.New(em.typ)
+			if err := unmarshalNode(copy, tmp, included, opts); err != nil {
 				return err
 			}
 
 			// had changes; assign value to struct field, replace orig node (data) w/ mutated copy
 			if !reflect.DeepEqual(copy, data) {
-				assign(em.structField, tmp)
+				assign(fieldValue, tmp)
 				data = copy
 			}
 		} else {
 			// handle non-nil scenarios
-			if err := unmarshalNode(data, em.model, included); err != nil {
+			emModel := fieldValue
+			if !em.ptr {
+				emModel = fieldValue.Addr()
+			}
+			if err := unmarshalNode(data, emModel, included, opts); err != nil {
 				return err
 			}
 		}
@@ -248,6 +563,22 @@ func handleClientIDUnmarshal(data *Node, args []string, fieldValue reflect.Value
 	return nil
 }
 
+func handleLidUnmarshal(data *Node, args []string, fieldValue reflect.Value) error {
+	if len(args) != 1 {
+		return ErrBadJSONAPIStructTag
+	}
+
+	if data.Lid == "" {
+		return nil
+	}
+
+	// set value and clear lid to denote it's already been processed
+	fieldValue.Set(reflect.ValueOf(data.Lid))
+	data.Lid = ""
+
+	return nil
+}
+
 func handlePrimaryUnmarshal(data *Node, args []string, fieldType reflect.StructField, fieldValue reflect.Value) error {
 	if len(args) < 2 {
 		return ErrBadJSONAPIStructTag
@@ -259,11 +590,14 @@ func handlePrimaryUnmarshal(data *Node, args []string, fieldType reflect.StructF
 
 	// Check the JSON API Type
 	if data.Type != args[1] {
-		return fmt.Errorf(
-			"Trying to Unmarshal an object of type %#v, but %#v does not match",
-			data.Type,
-			args[1],
-		)
+		return &UnmarshalError{
+			Pointer: data.pointer + "/type",
+			Err: fmt.Errorf(
+				"Trying to Unmarshal an object of type %#v, but %#v does not match",
+				data.Type,
+				args[1],
+			),
+		}
 	}
 
 	// Deal with PTRS
@@ -286,7 +620,7 @@ func handlePrimaryUnmarshal(data *Node, args []string, fieldType reflect.StructF
 
 		fv, err := strconv.ParseFloat(data.ID, 64)
 		if err != nil {
-			return ErrBadJSONAPIID
+			return &UnmarshalError{Pointer: data.pointer + "/id", Err: ErrBadJSONAPIID}
 		}
 
 		b, err := json.Marshal(fv)
@@ -296,7 +630,7 @@ func handlePrimaryUnmarshal(data *Node, args []string, fieldType reflect.StructF
 
 		v := fieldValue.Addr().Interface()
 		if err := json.Unmarshal(b, v); err != nil {
-			return err
+			return &UnmarshalError{Pointer: data.pointer + "/id", Err: err}
 		}
 	}
 
@@ -305,12 +639,12 @@ func handlePrimaryUnmarshal(data *Node, args []string, fieldType reflect.StructF
 	return nil
 }
 
-func handleRelationUnmarshal(data *Node, args []string, fieldValue reflect.Value, included *map[string]*Node) error {
+func handleRelationUnmarshal(data *Node, args []string, fieldValue reflect.Value, included *map[string]*Node, opts *unmarshalOptions) error {
 	if len(args) < 2 {
 		return ErrBadJSONAPIStructTag
 	}
 
-	if data.Relationships == nil || data.Relationships[args[1]] == nil {
+	if data.Relationships == nil || isNull(data.Relationships[args[1]]) {
 		return nil
 	}
 
@@ -322,7 +656,8 @@ func handleRelationUnmarshal(data *Node, args []string, fieldValue reflect.Value
 		handler = handleToManyRelationUnmarshal
 	}
 
-	v, err := handler(data.Relationships[args[1]], fieldValue.Type(), included)
+	pointer := data.pointer + "/relationships/" + escapeJSONPointer(args[1])
+	v, err := handler(data.Relationships[args[1]], fieldValue.Type(), included, pointer, opts)
 	if err != nil {
 		return err
 	}
@@ -335,14 +670,13 @@ func handleRelationUnmarshal(data *Node, args []string, fieldValue reflect.Value
 }
 
 // to-one relationships
-func handleToOneRelationUnmarshal(relationData interface{}, fieldType reflect.Type, included *map[string]*Node) (*reflect.Value, error) {
+func handleToOneRelationUnmarshal(relationData interface{}, fieldType reflect.Type, included *map[string]*Node,
+	pointer string, opts *unmarshalOptions) (*reflect.Value, error) {
 	relationship := new(RelationshipOneNode)
+	if err := unmarshalRelationship(relationData, relationship, pointer); err != nil {
+		return nil, err
+	}
 
-	buf := bytes.NewBuffer(nil)
-	json.NewEncoder(buf).Encode(relationData)
-	json.NewDecoder(buf).Decode(relationship)
-
-	m := reflect.New(fieldType.Elem())
 	/*
 		http://jsonapi.org/format/#document-resource-object-relationships
 		http://jsonapi.org/format/#document-resource-object-linkage
@@ -352,11 +686,23 @@ func handleToOneRelationUnmarshal(relationData interface{}, fieldType reflect.Ty
 	if relationship.Data == nil {
 		return nil, nil
 	}
+	relationship.Data.pointer = pointer + "/data"
+
+	m, err := opts.newModel(fieldType, relationship.Data)
+	if err != nil {
+		return nil, err
+	}
+	if opts.strict && fieldType.Kind() != reflect.Interface {
+		if err := checkLinkageType(relationship.Data, fieldType.Elem()); err != nil {
+			return nil, err
+		}
+	}
 
-	if err := unmarshalNode(
+	if err := unmarshalResource(
 		fullNode(relationship.Data, included),
 		m,
 		included,
+		opts,
 	); err != nil {
 		return nil, err
 	}
@@ -365,23 +711,34 @@ func handleToOneRelationUnmarshal(relationData interface{}, fieldType reflect.Ty
 }
 
 // to-many relationship
-func handleToManyRelationUnmarshal(relationData interface{}, fieldType reflect.Type, included *map[string]*Node) (*reflect.Value, error) {
+func handleToManyRelationUnmarshal(relationData interface{}, fieldType reflect.Type, included *map[string]*Node,
+	pointer string, opts *unmarshalOptions) (*reflect.Value, error) {
 	relationship := new(RelationshipManyNode)
-
-	buf := bytes.NewBuffer(nil)
-	json.NewEncoder(buf).Encode(relationData)
-	json.NewDecoder(buf).Decode(relationship)
+	if err := unmarshalRelationship(relationData, relationship, pointer); err != nil {
+		return nil, err
+	}
 
 	models := reflect.New(fieldType).Elem()
 
 	rData := relationship.Data
+	setNodePointers(rData, pointer+"/data")
 	for _, n := range rData {
-		m := reflect.New(fieldType.Elem().Elem())
+		m, err := opts.newModel(fieldType.Elem(), n)
+		if err != nil {
+			return nil, err
+		}
 
-		if err := unmarshalNode(
+		if opts.strict && fieldType.Elem().Kind() != reflect.Interface {
+			if err := checkLinkageType(n, fieldType.Elem().Elem()); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := unmarshalResource(
 			fullNode(n, included),
 			m,
 			included,
+			opts,
 		); err != nil {
 			return nil, err
 		}
@@ -392,6 +749,23 @@ func handleToManyRelationUnmarshal(relationData interface{}, fieldType reflect.T
 	return &models, nil
 }
 
+// unmarshalRelationship decodes the relationship object at pointer into
+// relationship.
+func unmarshalRelationship(relationData, relationship interface{}, pointer string) error {
+	raw, err := rawValue(relationData)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, relationship); err != nil {
+		uErr := &UnmarshalError{Pointer: pointer, Err: err}
+		if actual := jsonType(relationData); actual != "object" {
+			uErr.Expected, uErr.Actual = "object", actual
+		}
+		return uErr
+	}
+	return nil
+}
+
 // handleAttributeUnmarshal
 func handleAttributeUnmarshal(data *Node, args []string, fieldType reflect.StructField, fieldValue reflect.Value) error {
 	if len(args) < 2 {
@@ -404,21 +778,184 @@ func handleAttributeUnmarshal(data *Node, args []string, fieldType reflect.Struc
 	val := attributes[args[1]]
 
 	// continue if the attribute was not included in the request
-	if val == nil {
+	if isNull(val) {
 		return nil
 	}
 
-	// custom handling of time
+	var err error
 	if isTimeValue(fieldValue) {
-		return handleTimeAttributes(data, args, fieldValue, fieldType)
+		// custom handling of time
+		err = handleTimeAttributes(data, args, fieldValue, fieldType)
+	} else {
+		// standard attributes that the json package knows how to handle, plus implementions on json.Unmarshaler
+		err = handleWithJSONMarshaler(data, args, fieldValue)
+	}
+	if err != nil {
+		uErr := &UnmarshalError{
+			Pointer: data.pointer + "/attributes/" + escapeJSONPointer(args[1]),
+			Err:     err,
+		}
+		// only a type mismatch; e.g. not a malformed ISO8601 string
+		if expected, actual := expectedJSONType(fieldValue.Type(), useISO8601(args)), jsonType(val); expected != actual {
+			uErr.Expected, uErr.Actual = expected, actual
+		}
+		return uErr
 	}
+	return nil
+}
 
-	// standard attributes that the json package knows how to handle, plus implementions on json.Unmarshaler
-	return handleWithJSONMarshaler(data, args, fieldValue)
+// unknownMembers returns an error for each attribute and relationship of data
+// that is not declared by the struct type t or its embedded structs.
+func unknownMembers(data *Node, t reflect.Type) ErrorObjects {
+	if data == nil {
+		return nil
+	}
+
+	schema := schemaOf(t)
+
+	var errs ErrorObjects
+	unknown := func(kind, name string) {
+		errs = append(errs, &ErrorObject{
+			Title:  fmt.Sprintf("Unknown %s", kind),
+			Detail: fmt.Sprintf("%q is not a %s of %q", name, kind, schema.primary),
+			Status: "400",
+			Source: &ErrorSource{Pointer: fmt.Sprintf("%s/%ss/%s", data.pointer, kind, escapeJSONPointer(name))},
+		})
+	}
+
+	for _, name := range sortedKeys(data.Attributes) {
+		if _, ok := schema.attrs[name]; !ok {
+			unknown("attribute", name)
+		}
+	}
+	for _, name := range sortedKeys(data.Relationships) {
+		if _, ok := schema.rels[name]; !ok {
+			unknown("relationship", name)
+		}
+	}
+
+	return errs
+}
+
+// checkLinkageType returns an error if the type of the resource linkage n is
+// not the type of the struct type t.
+func checkLinkageType(n *Node, t reflect.Type) error {
+	if expected := primaryTypeName(t); n.Type != expected {
+		return ErrorObjects{&ErrorObject{
+			Title:  "Invalid relationship type",
+			Detail: fmt.Sprintf("Expected a resource of type %q, got %q", expected, n.Type),
+			Status: "400",
+			Source: &ErrorSource{Pointer: n.pointer + "/type"},
+		}}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// setNodePointers sets the JSON Pointers of the nodes of the array at pointer
+func setNodePointers(nodes []*Node, pointer string) {
+	for i, n := range nodes {
+		if n != nil {
+			n.pointer = fmt.Sprintf("%s/%d", pointer, i)
+		}
+	}
+}
+
+func nodePointer(n *Node) string {
+	if n == nil {
+		return ""
+	}
+	return n.pointer
+}
+
+// escapeJSONPointer escapes a member name for use as a JSON Pointer reference
+// token; see https://tools.ietf.org/html/rfc6901#section-3
+func escapeJSONPointer(name string) string {
+	return strings.Replace(strings.Replace(name, "~", "~0", -1), "/", "~1", -1)
+}
+
+// jsonType returns the JSON type of a value decoded by encoding/json, or of
+// a json.RawMessage.
+func jsonType(v interface{}) string {
+	if raw, ok := v.(json.RawMessage); ok {
+		if len(raw) == 0 {
+			return "null"
+		}
+		switch raw[0] {
+		case 'n':
+			return "null"
+		case 't', 'f':
+			return "boolean"
+		case '"':
+			return "string"
+		case '[':
+			return "array"
+		case '{':
+			return "object"
+		default:
+			return "number"
+		}
+	}
+
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// expectedJSONType returns the JSON type that is unmarshaled into a field of
+// type t; types implementing json.Unmarshaler are described by their name.
+func expectedJSONType(t reflect.Type, iso8601 bool) string {
+	if t == timeType || t == ptrTimeType {
+		if iso8601 {
+			return "string"
+		}
+		return "number"
+	}
+	if implementsJSONUnmarshaler(t) {
+		return t.String()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
 }
 
 func fullNode(n *Node, included *map[string]*Node) *Node {
-	includedKey := fmt.Sprintf("%s,%s", n.Type, n.ID)
+	includedKey := nodeKey(n)
 
 	if included != nil && (*included)[includedKey] != nil {
 		return deepCopyNode((*included)[includedKey])
@@ -444,7 +981,7 @@ func assign(field, value reflect.Value) {
 // IMHO (skimata): just default on recommended ISO8601, all others desired formats
 // should implement w/ a custom marshaler/unmarshaler
 func handleTimeAttributes(data *Node, args []string, fieldValue reflect.Value, structField reflect.StructField) error {
-	b, err := json.Marshal(data.Attributes[args[1]])
+	b, err := rawValue(data.Attributes[args[1]])
 	if err != nil {
 		return err
 	}
@@ -476,7 +1013,7 @@ func handleTimeAttributes(data *Node, args []string, fieldValue reflect.Value, s
 func handleWithJSONMarshaler(data *Node, args []string, fieldValue reflect.Value) error {
 	v := fieldValue.Addr().Interface()
 
-	b, err := json.Marshal(data.Attributes[args[1]])
+	b, err := rawValue(data.Attributes[args[1]])
 	if err != nil {
 		return err
 	}
@@ -280,6 +280,24 @@ func TestUnmarshal_nonNumericID(t *testing.T) {
 	}
 }
 
+func TestUnmarshalErrorPointers(t *testing.T) {
+	data := samplePayloadWithoutIncluded()
+	data["data"].(map[string]interface{})["id"] = "non-numeric-id"
+	payload, _ := payload(data)
+
+	err := UnmarshalPayload(bytes.NewReader(payload), new(Post), UnmarshalErrorPointers())
+	uErr, ok := err.(*UnmarshalError)
+	if !ok {
+		t.Fatalf("Expected an *UnmarshalError, got %#v", err)
+	}
+	if uErr.Err != ErrBadJSONAPIID {
+		t.Fatalf("Was expecting the underlying error %v, got %v", ErrBadJSONAPIID, uErr.Err)
+	}
+	if e, a := "/data/id", uErr.Pointer; e != a {
+		t.Fatalf("Was expecting the pointer %q, got %q", e, a)
+	}
+}
+
 func TestUnmarshalSetsAttrs(t *testing.T) {
 	out, err := unmarshalSamplePayload()
 	if err != nil {
@@ -595,6 +613,62 @@ func TestUnmarshalNestedRelationshipsEmbedded_withClientIDs(t *testing.T) {
 	}
 }
 
+func TestUnmarshalPayload_lid(t *testing.T) {
+	in, err := payload(map[string]interface{}{
+		"data": map[string]interface{}{
+			"type":       "articles",
+			"lid":        "a1",
+			"attributes": map[string]interface{}{"title": "New"},
+			"relationships": map[string]interface{}{
+				"author": map[string]interface{}{
+					"data": map[string]interface{}{"type": "people", "lid": "p1"},
+				},
+				"contributors": map[string]interface{}{
+					"data": []interface{}{
+						map[string]interface{}{"type": "people", "lid": "p1"},
+						map[string]interface{}{"type": "people", "lid": "p2"},
+						map[string]interface{}{"type": "people", "id": "3"},
+					},
+				},
+			},
+		},
+		"included": []interface{}{
+			map[string]interface{}{
+				"type":       "people",
+				"lid":        "p1",
+				"attributes": map[string]interface{}{"name": "Ann"},
+			},
+			map[string]interface{}{
+				"type":       "people",
+				"lid":        "p2",
+				"attributes": map[string]interface{}{"name": "Bob"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(Article)
+	if err := UnmarshalPayload(bytes.NewReader(in), out); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Article{
+		Lid:    "a1",
+		Title:  "New",
+		Author: &Person{Lid: "p1", Name: "Ann"},
+		Contributors: []*Person{
+			{Lid: "p1", Name: "Ann"},
+			{Lid: "p2", Name: "Bob"},
+			{ID: "3"},
+		},
+	}
+	if !reflect.DeepEqual(expected, out) {
+		t.Fatalf("Was expecting %#v, got %#v", expected, out)
+	}
+}
+
 func unmarshalSamplePayload() (*Blog, error) {
 	in := samplePayload()
 	out := new(Blog)
@@ -1067,3 +1141,296 @@ func sampleSerializedEmbeddedTestModel() *Blog {
 
 	return blog
 }
+
+func TestUnmarshalError_pointer(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		payload  map[string]interface{}
+		many     bool
+		expected string
+	}{
+		{
+			name: "embedded relationship",
+			payload: map[string]interface{}{
+				"data": map[string]interface{}{
+					"type": "blogs",
+					"relationships": map[string]interface{}{
+						"current_post": map[string]interface{}{
+							"data": map[string]interface{}{
+								"type": "posts",
+								"relationships": map[string]interface{}{
+									"comments": map[string]interface{}{
+										"data": []interface{}{
+											map[string]interface{}{"type": "comments", "attributes": map[string]interface{}{"body": "ok"}},
+											map[string]interface{}{"type": "comments", "attributes": map[string]interface{}{"body": 5}},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: "/data/relationships/current_post/data/relationships/comments/data/1/attributes/body",
+		},
+		{
+			name: "included",
+			payload: map[string]interface{}{
+				"data": map[string]interface{}{
+					"type": "blogs",
+					"id":   "1",
+					"relationships": map[string]interface{}{
+						"posts": map[string]interface{}{
+							"data": []interface{}{
+								map[string]interface{}{"type": "posts", "id": "2"},
+							},
+						},
+					},
+				},
+				"included": []interface{}{
+					map[string]interface{}{"type": "posts", "id": "2", "attributes": map[string]interface{}{"title": true}},
+				},
+			},
+			expected: "/included/0/attributes/title",
+		},
+		{
+			name: "many",
+			payload: map[string]interface{}{
+				"data": []interface{}{
+					map[string]interface{}{"type": "blogs", "id": "1"},
+					map[string]interface{}{"type": "blogs", "id": "2", "attributes": map[string]interface{}{"view_count": "1"}},
+				},
+			},
+			many:     true,
+			expected: "/data/1/attributes/view_count",
+		},
+		{
+			name: "type",
+			payload: map[string]interface{}{
+				"data": map[string]interface{}{"type": "posts", "id": "1"},
+			},
+			expected: "/data/type",
+		},
+		{
+			name: "relationship",
+			payload: map[string]interface{}{
+				"data": map[string]interface{}{
+					"type": "blogs",
+					"relationships": map[string]interface{}{
+						"posts": map[string]interface{}{
+							"data": map[string]interface{}{"type": "posts", "id": "1"},
+						},
+					},
+				},
+			},
+			expected: "/data/relationships/posts",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			in, err := payload(scenario.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if scenario.many {
+				_, err = UnmarshalManyPayload(bytes.NewReader(in), reflect.TypeOf(new(Blog)), UnmarshalErrorPointers())
+			} else {
+				err = UnmarshalPayload(bytes.NewReader(in), new(Blog), UnmarshalErrorPointers())
+			}
+
+			uErr, ok := err.(*UnmarshalError)
+			if !ok {
+				t.Fatalf("Expected an *UnmarshalError, got %#v", err)
+			}
+			if e, a := scenario.expected, uErr.Pointer; e != a {
+				t.Fatalf("Was expecting the pointer %q, got %q", e, a)
+			}
+		})
+	}
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	in, err := payload(map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "blogs",
+			"id":   "1",
+			"attributes": map[string]interface{}{
+				"titel":      "Typo",
+				"view_count": 5,
+			},
+			"relationships": map[string]interface{}{
+				"author": map[string]interface{}{
+					"data": map[string]interface{}{"type": "users", "id": "1"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ignored by default
+	if err := UnmarshalPayload(bytes.NewReader(in), new(Blog)); err != nil {
+		t.Fatal(err)
+	}
+
+	err = UnmarshalPayload(bytes.NewReader(in), new(Blog), UnmarshalStrict())
+	errs, ok := err.(ErrorObjects)
+	if !ok {
+		t.Fatalf("Was expecting ErrorObjects, got %#v", err)
+	}
+
+	pointers := []string{}
+	for _, e := range errs {
+		if e.Status != "400" {
+			t.Fatalf("Was expecting a 400 status, got %q", e.Status)
+		}
+		pointers = append(pointers, e.Source.Pointer)
+	}
+	expected := []string{"/data/attributes/titel", "/data/relationships/author"}
+	if !reflect.DeepEqual(expected, pointers) {
+		t.Fatalf("Was expecting the pointers %v, got %v", expected, pointers)
+	}
+}
+
+func TestUnmarshalStrict_embeddedStructs(t *testing.T) {
+	in, err := payload(map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "car",
+			"attributes": map[string]interface{}{
+				"make":      "VW",
+				"cylinders": 6,
+				"watts":     150,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := UnmarshalPayload(bytes.NewReader(in), new(Vehicle), UnmarshalStrict()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnmarshalStrict_relationshipType(t *testing.T) {
+	in, err := payload(map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "blogs",
+			"relationships": map[string]interface{}{
+				"posts": map[string]interface{}{
+					"data": []interface{}{
+						map[string]interface{}{"type": "posts", "client-id": "1"},
+						map[string]interface{}{"type": "comments", "client-id": "2"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = UnmarshalPayload(bytes.NewReader(in), new(Blog), UnmarshalStrict())
+	errs, ok := err.(ErrorObjects)
+	if !ok {
+		t.Fatalf("Was expecting ErrorObjects, got %#v", err)
+	}
+	if e, a := "/data/relationships/posts/data/1/type", errs[0].Source.Pointer; e != a {
+		t.Fatalf("Was expecting the pointer %q, got %q", e, a)
+	}
+}
+
+func TestUnmarshalPartialPayload(t *testing.T) {
+	in, err := payload(map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "blogs",
+			"id":   "1",
+			"attributes": map[string]interface{}{
+				"title":      "New title",
+				"created_at": nil,
+				"unknown":    "ignored",
+			},
+			"relationships": map[string]interface{}{
+				"current_post": map[string]interface{}{"data": nil},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blog := new(Blog)
+	presence, err := UnmarshalPartialPayload(bytes.NewReader(in), blog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if blog.Title != "New title" {
+		t.Fatalf("Was expecting the title to be unmarshaled, got %q", blog.Title)
+	}
+	if !presence.HasAttribute("title") || !presence.HasAttribute("created_at") {
+		t.Fatalf("Was expecting title and created_at to be present, got %v", presence.Attributes)
+	}
+	if presence.HasAttribute("view_count") || presence.HasAttribute("unknown") {
+		t.Fatalf("Was not expecting view_count or unknown to be present, got %v", presence.Attributes)
+	}
+	if !presence.HasRelationship("current_post") || presence.HasRelationship("posts") {
+		t.Fatalf("Was expecting only current_post to be present, got %v", presence.Relationships)
+	}
+
+	expected := []string{"CreatedAt", "CurrentPost", "Title"}
+	if paths := presence.FieldPaths(); !reflect.DeepEqual(expected, paths) {
+		t.Fatalf("Was expecting the field paths %v, got %v", expected, paths)
+	}
+}
+
+func TestUnmarshalPartialPayload_embeddedStructs(t *testing.T) {
+	in, err := payload(map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "car",
+			"attributes": map[string]interface{}{
+				"make":      "VW",
+				"cylinders": 6,
+				"watts":     150,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	presence, err := UnmarshalPartialPayload(bytes.NewReader(in), new(Vehicle))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"make":      "Make",
+		"cylinders": "Engine.NumberOfCylinders",
+		"watts":     "BlockHeater.Watts",
+	}
+	if !reflect.DeepEqual(expected, presence.Attributes) {
+		t.Fatalf("Was expecting the attributes %v, got %v", expected, presence.Attributes)
+	}
+}
+
+func TestUnmarshalPayload_largeIntegerAttribute(t *testing.T) {
+	type Counter struct {
+		ID    string `jsonapi:"primary,counters"`
+		Count int64  `jsonapi:"attr,count"`
+	}
+
+	// not representable as a float64
+	in := []byte(`{"data": {"type": "counters", "id": "1", "attributes": {"count": 9007199254740993}}}`)
+
+	counter := new(Counter)
+	if err := UnmarshalPayload(bytes.NewReader(in), counter); err != nil {
+		t.Fatal(err)
+	}
+	if counter.Count != 9007199254740993 {
+		t.Fatalf("Was expecting the count 9007199254740993, got %d", counter.Count)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +29,242 @@ var (
 	ErrUnexpectedType = errors.New("models should be a struct pointer or slice of struct pointers")
 )
 
+// MarshalOption is used to configure the optional behavior of Marshal,
+// MarshalPayload and MarshalPayloadWithoutIncluded.
+type MarshalOption func(*marshalOptions)
+
+// marshalOptions holds the settings collected from the given MarshalOptions.
+type marshalOptions struct {
+	// fields is the sparse fieldset for each resource type; a type that is not
+	// present is marshaled with all of its fields.
+	fields map[string]fieldset
+	// include holds the relationship paths to sideload; nil sideloads every
+	// related resource.
+	include includeTree
+	// sortIncluded sorts the included nodes by type and id, rather than
+	// keeping the order in which they were sideloaded.
+	sortIncluded bool
+	// links, meta and jsonapi are the top-level members of the document.
+	links   Links
+	meta    Meta
+	jsonapi *JSONAPIObject
+}
+
+func newMarshalOptions(opts []MarshalOption) *marshalOptions {
+	o := &marshalOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// fieldset is the set of attribute and relationship names to marshal for a
+// resource type; a nil fieldset allows every field.
+type fieldset map[string]bool
+
+func (f fieldset) has(name string) bool {
+	return f == nil || f[name]
+}
+
+func (o *marshalOptions) fieldset(modelType reflect.Type) fieldset {
+	if o == nil || o.fields == nil {
+		return nil
+	}
+	return o.fields[primaryTypeName(modelType)]
+}
+
+// includeTree is a parsed set of include paths. Each key is a relationship
+// name and its value holds the paths included from the related resources.
+type includeTree map[string]includeTree
+
+// child returns the include paths of the relationship name, and whether the
+// related resources are included at all. A nil includeTree includes every
+// relationship.
+func (t includeTree) child(name string) (includeTree, bool) {
+	if t == nil {
+		return nil, true
+	}
+	c, ok := t[name]
+	if !ok {
+		return includeTree{}, false
+	}
+	return c, true
+}
+
+// validate returns a 400 ErrorObject for the first path of t that is not a
+// chain of relationships starting at the struct type modelType.
+func (t includeTree) validate(modelType reflect.Type, prefix string) error {
+	for name, c := range t {
+		path := prefix + name
+
+		relType, ok := relationFieldType(modelType, name)
+		if !ok {
+			return &ErrorObject{
+				Title:  "Invalid include parameter",
+				Detail: fmt.Sprintf("%q is not a relationship of %q", path, primaryTypeName(modelType)),
+				Status: "400",
+				Source: &ErrorSource{Parameter: QueryParamInclude},
+			}
+		}
+		// the related types of a polymorphic relationship are only known once
+		// marshaled, so the paths below it are not validated
+		if relType.Kind() == reflect.Interface {
+			continue
+		}
+		if err := c.validate(relType, path+"."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalFields restricts the attributes and relationships written for each
+// resource type, as given in the "fields[TYPE]" query parameters, e.g.
+//
+//	jsonapi.MarshalPayload(w, blogs, jsonapi.MarshalFields(map[string][]string{
+//		"blogs": {"title", "posts"},
+//	}))
+//
+// The fieldsets apply to the primary data as well as to every resource in the
+// "included" array; the "type" and "id" members are always written. Types that
+// are not present in fields are written with all of their fields.
+//
+// See http://jsonapi.org/format/#fetching-sparse-fieldsets
+func MarshalFields(fields map[string][]string) MarshalOption {
+	return func(o *marshalOptions) {
+		if o.fields == nil {
+			o.fields = make(map[string]fieldset)
+		}
+		for t, names := range fields {
+			set := fieldset{}
+			for _, name := range names {
+				if name != "" {
+					set[name] = true
+				}
+			}
+			o.fields[t] = set
+		}
+	}
+}
+
+// topLevel merges the document links and meta given as options into links and
+// meta, with the options taking priority, and validates the resulting links.
+func (o *marshalOptions) topLevel(links *Links, meta *Meta) (*Links, *Meta, error) {
+	if len(o.links) > 0 {
+		merged := Links{}
+		if links != nil {
+			for k, v := range *links {
+				merged[k] = v
+			}
+		}
+		for k, v := range o.links {
+			merged[k] = v
+		}
+		if err := merged.validate(); err != nil {
+			return nil, nil, err
+		}
+		links = &merged
+	}
+
+	if len(o.meta) > 0 {
+		merged := Meta{}
+		if meta != nil {
+			for k, v := range *meta {
+				merged[k] = v
+			}
+		}
+		for k, v := range o.meta {
+			merged[k] = v
+		}
+		meta = &merged
+	}
+
+	return links, meta, nil
+}
+
+// MarshalLinks adds links, such as "self" or pagination links, to the
+// top-level "links" object of the document. Links given by a Linkable slice of
+// models are kept unless overridden by a member of the same name.
+func MarshalLinks(links Links) MarshalOption {
+	return func(o *marshalOptions) {
+		if o.links == nil {
+			o.links = Links{}
+		}
+		for k, v := range links {
+			o.links[k] = v
+		}
+	}
+}
+
+// MarshalMeta adds meta to the top-level "meta" object of the document. Meta
+// given by a Metable slice of models is kept unless overridden by a member of
+// the same name.
+func MarshalMeta(meta Meta) MarshalOption {
+	return func(o *marshalOptions) {
+		if o.meta == nil {
+			o.meta = Meta{}
+		}
+		for k, v := range meta {
+			o.meta[k] = v
+		}
+	}
+}
+
+// MarshalJSONAPI sets the top-level "jsonapi" object of the document, which
+// describes the server's implementation.
+//
+// See http://jsonapi.org/format/#document-jsonapi-object
+func MarshalJSONAPI(obj JSONAPIObject) MarshalOption {
+	return func(o *marshalOptions) {
+		o.jsonapi = &obj
+	}
+}
+
+// MarshalSortIncluded sorts the "included" array by type, then by id. By
+// default the included resources are written in the order in which they are
+// first reached while traversing the relationships of the marshaled models,
+// each resource before the resources related to it.
+// Either way, the same models always result in the same document.
+func MarshalSortIncluded() MarshalOption {
+	return func(o *marshalOptions) {
+		o.sortIncluded = true
+	}
+}
+
+// MarshalInclude restricts the related resources sideloaded into the
+// "included" array to those reachable along the given relationship paths, as
+// given in the "include" query parameter, e.g.
+//
+//	jsonapi.MarshalPayload(w, blog, jsonapi.MarshalInclude("posts.comments", "current_post"))
+//
+// A path is a dot-separated list of relationship names; comma-separated lists
+// of paths are accepted as well. Relationships that are not part of a path are
+// written as resource linkage only. Marshaling returns an *ErrorObject with a
+// 400 status if a path does not name a relationship of the marshaled models.
+//
+// See http://jsonapi.org/format/#fetching-includes
+func MarshalInclude(paths ...string) MarshalOption {
+	return func(o *marshalOptions) {
+		if o.include == nil {
+			o.include = includeTree{}
+		}
+		for _, list := range paths {
+			for _, path := range strings.Split(list, annotationSeperator) {
+				if path == "" {
+					continue
+				}
+				t := o.include
+				for _, name := range strings.Split(path, ".") {
+					if t[name] == nil {
+						t[name] = includeTree{}
+					}
+					t = t[name]
+				}
+			}
+		}
+	}
+}
+
 // MarshalPayload writes a jsonapi response for one or many records. The
 // related records are sideloaded into the "included" array. If this method is
 // given a struct pointer as an argument it will serialize in the form
@@ -62,8 +299,10 @@ var (
 //		 }
 //	 }
 //
-func MarshalPayload(w io.Writer, models interface{}) error {
-	payload, err := Marshal(models)
+// Optional MarshalOptions, such as MarshalFields, can be given to customize the
+// resulting document.
+func MarshalPayload(w io.Writer, models interface{}, opts ...MarshalOption) error {
+	payload, err := Marshal(models, opts...)
 	if err != nil {
 		return err
 	}
@@ -77,7 +316,9 @@ func MarshalPayload(w io.Writer, models interface{}) error {
 // Marshal does the same as MarshalPayload except it just returns the payload
 // and doesn't write out results. Useful if you use your own JSON rendering
 // library.
-func Marshal(models interface{}) (Payloader, error) {
+func Marshal(models interface{}, opts ...MarshalOption) (Payloader, error) {
+	o := newMarshalOptions(opts)
+
 	switch vals := reflect.ValueOf(models); vals.Kind() {
 	case reflect.Slice:
 		m, err := convertToSliceInterface(&models)
@@ -85,7 +326,7 @@ func Marshal(models interface{}) (Payloader, error) {
 			return nil, err
 		}
 
-		payload, err := marshalMany(m)
+		payload, err := marshalMany(m, o)
 		if err != nil {
 			return nil, err
 		}
@@ -102,13 +343,29 @@ func Marshal(models interface{}) (Payloader, error) {
 			payload.Meta = metableModels.JSONAPIMeta()
 		}
 
+		if payload.Links, payload.Meta, err = o.topLevel(payload.Links, payload.Meta); err != nil {
+			return nil, err
+		}
+		payload.JSONAPI = o.jsonapi
+
 		return payload, nil
 	case reflect.Ptr:
 		// Check that the pointer was to a struct
 		if reflect.Indirect(vals).Kind() != reflect.Struct {
 			return nil, ErrUnexpectedType
 		}
-		return marshalOne(models)
+
+		payload, err := marshalOne(models, o)
+		if err != nil {
+			return nil, err
+		}
+
+		if payload.Links, payload.Meta, err = o.topLevel(payload.Links, payload.Meta); err != nil {
+			return nil, err
+		}
+		payload.JSONAPI = o.jsonapi
+
+		return payload, nil
 	default:
 		return nil, ErrUnexpectedType
 	}
@@ -121,8 +378,8 @@ func Marshal(models interface{}) (Payloader, error) {
 //
 // models interface{} should be either a struct pointer or a slice of struct
 // pointers.
-func MarshalPayloadWithoutIncluded(w io.Writer, model interface{}) error {
-	payload, err := Marshal(model)
+func MarshalPayloadWithoutIncluded(w io.Writer, model interface{}, opts ...MarshalOption) error {
+	payload, err := Marshal(model, opts...)
 	if err != nil {
 		return err
 	}
@@ -137,16 +394,20 @@ func MarshalPayloadWithoutIncluded(w io.Writer, model interface{}) error {
 // marshalOne does the same as MarshalOnePayload except it just returns the
 // payload and doesn't write out results. Useful is you use your JSON rendering
 // library.
-func marshalOne(model interface{}) (*OnePayload, error) {
-	included := make(map[string]*Node)
+func marshalOne(model interface{}, opts *marshalOptions) (*OnePayload, error) {
+	if err := opts.include.validate(reflect.TypeOf(model).Elem(), ""); err != nil {
+		return nil, err
+	}
+
+	included := newIncludedNodes()
 
-	rootNode, err := visitModelNode(model, &included, true)
+	rootNode, err := visitModelNode(model, included, true, opts, opts.include)
 	if err != nil {
 		return nil, err
 	}
 	payload := &OnePayload{Data: rootNode}
 
-	payload.Included = nodeMapValues(&included)
+	payload.Included = included.values(opts.sortIncluded)
 
 	return payload, nil
 }
@@ -154,20 +415,31 @@ func marshalOne(model interface{}) (*OnePayload, error) {
 // marshalMany does the same as MarshalManyPayload except it just returns the
 // payload and doesn't write out results. Useful is you use your JSON rendering
 // library.
-func marshalMany(models []interface{}) (*ManyPayload, error) {
+func marshalMany(models []interface{}, opts *marshalOptions) (*ManyPayload, error) {
 	payload := &ManyPayload{
 		Data: []*Node{},
 	}
-	included := map[string]*Node{}
+	included := newIncludedNodes()
+
+	validated := map[reflect.Type]bool{}
+	for _, model := range models {
+		modelType := reflect.TypeOf(model).Elem()
+		if !validated[modelType] {
+			if err := opts.include.validate(modelType, ""); err != nil {
+				return nil, err
+			}
+			validated[modelType] = true
+		}
+	}
 
 	for _, model := range models {
-		node, err := visitModelNode(model, &included, true)
+		node, err := visitModelNode(model, included, true, opts, opts.include)
 		if err != nil {
 			return nil, err
 		}
 		payload.Data = append(payload.Data, node)
 	}
-	payload.Included = nodeMapValues(&included)
+	payload.Included = included.values(opts.sortIncluded)
 
 	return payload, nil
 }
@@ -188,7 +460,7 @@ func marshalMany(models []interface{}) (*ManyPayload, error) {
 //
 // model interface{} should be a pointer to a struct.
 func MarshalOnePayloadEmbedded(w io.Writer, model interface{}) error {
-	rootNode, err := visitModelNode(model, nil, false)
+	rootNode, err := visitModelNode(model, nil, false, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -205,46 +477,50 @@ func MarshalOnePayloadEmbedded(w io.Writer, model interface{}) error {
 // visitModelNode converts models to jsonapi payloads
 // it handles the deepest models first. (i.e.) embedded models
 // this is so that upper-level attributes can overwrite lower-level attributes
-func visitModelNode(model interface{}, included *map[string]*Node, sideload bool) (*Node, error) {
+// only the related resources along the include paths are sideloaded
+func visitModelNode(model interface{}, included *includedNodes, sideload bool,
+	opts *marshalOptions, include includeTree) (*Node, error) {
+	// the models of polymorphic relationships are only checked here
+	if v := reflect.ValueOf(model); v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, ErrUnexpectedType
+	}
+	fields := opts.fieldset(reflect.TypeOf(model).Elem())
+	return visitModelFields(model, included, sideload, opts, include, fields)
+}
+
+// visitModelFields does the work of visitModelNode; embedded structs are
+// visited with the fieldset of the resource that embeds them.
+func visitModelFields(model interface{}, included *includedNodes, sideload bool,
+	opts *marshalOptions, include includeTree, fields fieldset) (*Node, error) {
 	var er error
 
 	modelValue := reflect.ValueOf(model).Elem()
-	modelType := reflect.ValueOf(model).Type().Elem()
+	schema := schemaOf(modelValue.Type())
 
 	node := new(Node)
 	nodes := make([]*Node, 0)
 	// handle just the embedded models first
-	for i := 0; i < modelValue.NumField(); i++ {
-		fieldValue := modelValue.Field(i)
-		fieldType := modelType.Field(i)
-
-		// skip if annotated w/ ignore
-		tag := fieldType.Tag.Get(annotationJSONAPI)
-		if shouldIgnoreField(tag) {
-			continue
-		}
+	for _, em := range schema.embeddeds {
+		fieldValue := modelValue.Field(em.index)
 
 		// handles embedded structs and pointers to embedded structs
-		if isEmbeddedStruct(fieldType) || isEmbeddedStructPtr(fieldType) {
-			var embModel interface{}
-			if fieldType.Type.Kind() == reflect.Ptr {
-				if fieldValue.IsNil() {
-					continue
-				}
-				embModel = fieldValue.Interface()
-			} else {
-				embModel = fieldValue.Addr().Interface()
-			}
-
-			embNode, err := visitModelNode(embModel, included, sideload)
-			if err != nil {
-				er = err
-				break
+		var embModel interface{}
+		if em.ptr {
+			if fieldValue.IsNil() {
+				continue
 			}
-			// append to array to process together in order to find dominant field conflicts
-			nodes = append(nodes, embNode)
+			embModel = fieldValue.Interface()
+		} else {
+			embModel = fieldValue.Addr().Interface()
+		}
 
+		embNode, err := visitModelFields(embModel, included, sideload, opts, include, fields)
+		if err != nil {
+			er = err
+			break
 		}
+		// append to array to process together in order to find dominant field conflicts
+		nodes = append(nodes, embNode)
 	}
 	// treat all embedded structs on this level as peers
 	// combine w/ func that track for dominant field conflicts
@@ -254,54 +530,27 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 	// track all attributes through attr vs node.Attributes, so that we can track dominant field conflicts on this level
 	attrs := attributes{}
 	// handle everthing else
-	for i := 0; i < modelValue.NumField(); i++ {
-		fieldValue := modelValue.Field(i)
-		fieldType := modelType.Field(i)
-
-		tag := fieldType.Tag.Get(annotationJSONAPI)
-
-		if shouldIgnoreField(tag) {
-			continue
-		}
-
-		// skip embedded because it was handled in a previous loop
-		if isEmbeddedStruct(fieldType) || isEmbeddedStructPtr(fieldType) {
-			continue
-		}
-
-		if tag == "" {
-			continue
-		}
-
-		args := strings.Split(tag, annotationSeperator)
-
-		if len(args) < 1 {
-			er = ErrBadJSONAPIStructTag
+	for _, f := range schema.fields {
+		if er != nil {
 			break
 		}
 
-		annotation := args[0]
+		fieldValue := modelValue.Field(f.index)
+		args := f.args
+		annotation := f.annotation
 
-		if (annotation == annotationClientID && len(args) != 1) ||
-			(annotation != annotationClientID && len(args) < 2) {
+		if ((annotation == annotationClientID || annotation == annotationLid) && len(args) != 1) ||
+			(annotation != annotationClientID && annotation != annotationLid && len(args) < 2) {
 			er = ErrBadJSONAPIStructTag
 			break
 		}
 
 		if annotation == annotationPrimary {
-			v := fieldValue
-
 			// Deal with PTRS
-			var kind reflect.Kind
-			if fieldValue.Kind() == reflect.Ptr {
-				kind = fieldType.Type.Elem().Kind()
-				v = reflect.Indirect(fieldValue)
-			} else {
-				kind = fieldType.Type.Kind()
-			}
+			v := reflect.Indirect(fieldValue)
 
 			// Handle allowed types
-			switch kind {
+			switch f.kind {
 			case reflect.String:
 				node.ID = v.Interface().(string)
 			case reflect.Int:
@@ -337,25 +586,22 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 			if clientID != "" {
 				node.ClientID = clientID
 			}
+		} else if annotation == annotationLid {
+			if lid := fieldValue.String(); lid != "" {
+				node.Lid = lid
+			}
 		} else if annotation == annotationAttribute {
-			var omitEmpty, iso8601 bool
-
-			if len(args) > 2 {
-				for _, arg := range args[2:] {
-					switch arg {
-					case annotationOmitEmpty:
-						omitEmpty = true
-					case annotationISO8601:
-						iso8601 = true
-					}
-				}
+			if !fields.has(args[1]) {
+				continue
 			}
 
+			omitEmpty, iso8601 := f.omitEmpty, f.iso8601
+
 			if node.Attributes == nil {
 				node.Attributes = make(map[string]interface{})
 			}
 			attributeKey := args[1]
-			if fieldValue.Type() == reflect.TypeOf(time.Time{}) {
+			if f.field.Type == timeType {
 				t := fieldValue.Interface().(time.Time)
 
 				if t.IsZero() {
@@ -367,7 +613,7 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 				} else {
 					attrs.set(attributeKey, t.Unix())
 				}
-			} else if fieldValue.Type() == reflect.TypeOf(new(time.Time)) {
+			} else if f.field.Type == ptrTimeType {
 				// A time pointer may be nil
 				if fieldValue.IsNil() {
 					if omitEmpty {
@@ -405,17 +651,17 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 				}
 			}
 		} else if annotation == annotationRelation {
-			var omitEmpty bool
+			if !fields.has(args[1]) {
+				continue
+			}
 
 			//add support for 'omitempty' struct tag for marshaling as absent
-			if len(args) > 2 {
-				omitEmpty = args[2] == annotationOmitEmpty
-			}
+			omitEmpty := f.omitEmpty
 
 			isSlice := fieldValue.Type().Kind() == reflect.Slice
 			if omitEmpty &&
 				(isSlice && fieldValue.Len() < 1 ||
-					(!isSlice && fieldValue.IsNil())) {
+					(!isSlice && isNilRelation(fieldValue))) {
 				continue
 			}
 
@@ -433,12 +679,18 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 				relMeta = metableModel.JSONAPIRelationshipMeta(args[1])
 			}
 
+			// related resources off the include paths are written as linkage only
+			relInclude, isIncluded := include.child(args[1])
+
 			if isSlice {
 				// to-many relationship
 				relationship, err := visitModelNodeRelationships(
 					fieldValue,
 					included,
 					sideload,
+					opts,
+					relInclude,
+					isIncluded,
 				)
 				if err != nil {
 					er = err
@@ -450,7 +702,6 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 				if sideload {
 					shallowNodes := []*Node{}
 					for _, n := range relationship.Data {
-						appendIncluded(included, n)
 						shallowNodes = append(shallowNodes, toShallowNode(n))
 					}
 
@@ -466,15 +717,23 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 				// to-one relationships
 
 				// Handle null relationship case
-				if fieldValue.IsNil() {
+				if isNilRelation(fieldValue) {
 					node.Relationships[args[1]] = &RelationshipOneNode{Data: nil}
 					continue
 				}
 
+				// the place of the related resource in "included" is taken
+				// before its own relationships are visited
+				var slot int
+				if sideload && isIncluded {
+					slot = included.reserve()
+				}
 				relationship, err := visitModelNode(
 					fieldValue.Interface(),
 					included,
 					sideload,
+					opts,
+					relInclude,
 				)
 				if err != nil {
 					er = err
@@ -482,7 +741,9 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 				}
 
 				if sideload {
-					appendIncluded(included, relationship)
+					if isIncluded {
+						included.fill(slot, relationship)
+					}
 					node.Relationships[args[1]] = &RelationshipOneNode{
 						Data:  toShallowNode(relationship),
 						Links: relLinks,
@@ -528,24 +789,42 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 	return node, nil
 }
 
+// isNilRelation reports whether the to-one relationship field v is null,
+// including a polymorphic relationship holding a nil pointer.
+func isNilRelation(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+		return v.Kind() == reflect.Ptr && v.IsNil()
+	}
+	return v.IsNil()
+}
+
 func toShallowNode(node *Node) *Node {
 	return &Node{
 		ID:   node.ID,
+		Lid:  node.Lid,
 		Type: node.Type,
 	}
 }
 
-func visitModelNodeRelationships(models reflect.Value, included *map[string]*Node,
-	sideload bool) (*RelationshipManyNode, error) {
+func visitModelNodeRelationships(models reflect.Value, included *includedNodes,
+	sideload bool, opts *marshalOptions, include includeTree, isIncluded bool) (*RelationshipManyNode, error) {
 	nodes := []*Node{}
 
 	for i := 0; i < models.Len(); i++ {
 		n := models.Index(i).Interface()
 
-		node, err := visitModelNode(n, included, sideload)
+		var slot int
+		if sideload && isIncluded {
+			slot = included.reserve()
+		}
+		node, err := visitModelNode(n, included, sideload, opts, include)
 		if err != nil {
 			return nil, err
 		}
+		if sideload && isIncluded {
+			included.fill(slot, node)
+		}
 
 		nodes = append(nodes, node)
 	}
@@ -553,33 +832,80 @@ func visitModelNodeRelationships(models reflect.Value, included *map[string]*Nod
 	return &RelationshipManyNode{Data: nodes}, nil
 }
 
-func appendIncluded(m *map[string]*Node, nodes ...*Node) {
-	included := *m
+// includedNodes collects the sideloaded nodes of a payload, keyed by nodeKey,
+// in the order they are first reached. The place of a node is reserved before
+// its relationships are visited, so that it precedes the resources it leads
+// to.
+type includedNodes struct {
+	slots []*Node
+	index map[string]int
+}
 
-	for _, n := range nodes {
-		k := fmt.Sprintf("%s,%s", n.Type, n.ID)
+func newIncludedNodes() *includedNodes {
+	return &includedNodes{index: make(map[string]int)}
+}
 
-		if _, hasNode := included[k]; hasNode {
-			continue
-		}
+// reserve reserves the place of a node that is about to be visited; fill puts
+// the node there once it is built.
+func (included *includedNodes) reserve() int {
+	included.slots = append(included.slots, nil)
+	return len(included.slots) - 1
+}
 
-		included[k] = n
+// fill puts n in the place slot, unless the same resource was reached first.
+// A resource can be filled in at a later place before an earlier one when it
+// is reached again along its own relationships; it is moved up.
+func (included *includedNodes) fill(slot int, n *Node) {
+	k := nodeKey(n)
+	if i, ok := included.index[k]; ok {
+		if i < slot {
+			return
+		}
+		included.slots[i] = nil
 	}
+
+	included.slots[slot] = n
+	included.index[k] = slot
 }
 
-func nodeMapValues(m *map[string]*Node) []*Node {
-	mp := *m
-	nodes := make([]*Node, len(mp))
+// values returns the included nodes in the order they were reached, or
+// sorted by type then id if sorted is true.
+func (included *includedNodes) values(sorted bool) []*Node {
+	nodes := make([]*Node, 0, len(included.index))
+	for _, n := range included.slots {
+		if n != nil {
+			nodes = append(nodes, n)
+		}
+	}
 
-	i := 0
-	for _, n := range mp {
-		nodes[i] = n
-		i++
+	if sorted {
+		sort.Stable(nodesByTypeAndID(nodes))
 	}
 
 	return nodes
 }
 
+// nodesByTypeAndID sorts nodes by type, then by id; ids that are both integers
+// are compared numerically.
+type nodesByTypeAndID []*Node
+
+func (n nodesByTypeAndID) Len() int      { return len(n) }
+func (n nodesByTypeAndID) Swap(i, j int) { n[i], n[j] = n[j], n[i] }
+func (n nodesByTypeAndID) Less(i, j int) bool { return lessByTypeAndID(n[i], n[j]) }
+
+func lessByTypeAndID(x, y *Node) bool {
+	if x.Type != y.Type {
+		return x.Type < y.Type
+	}
+
+	a, errA := strconv.ParseInt(x.ID, 10, 64)
+	b, errB := strconv.ParseInt(y.ID, 10, 64)
+	if errA == nil && errB == nil {
+		return a < b
+	}
+	return x.ID < y.ID
+}
+
 func convertToSliceInterface(i *interface{}) ([]interface{}, error) {
 	vals := reflect.ValueOf(*i)
 	if vals.Kind() != reflect.Slice {
@@ -603,3 +929,18 @@ func isEmbeddedStructPtr(sField reflect.StructField) bool {
 func shouldIgnoreField(japiTag string) bool {
 	return strings.HasPrefix(japiTag, annotationIgnore)
 }
+
+// relationFieldType returns the struct type of the resources related through
+// the relationship name of the struct type t, or the interface type of a
+// polymorphic relationship.
+func relationFieldType(t reflect.Type, name string) (reflect.Type, bool) {
+	relType := schemaOf(t).relTypes[name]
+	return relType, relType != nil
+}
+
+// primaryTypeName returns the JSON API type declared by the "primary"
+// annotation of the struct type t. As with marshaling, an annotation on t
+// takes precedence over those of its embedded structs.
+func primaryTypeName(t reflect.Type) string {
+	return schemaOf(t).primary
+}
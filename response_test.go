@@ -523,6 +523,27 @@ func TestInvalidLinkable(t *testing.T) {
 	}
 }
 
+func TestMarshal_nullRelationshipLinks(t *testing.T) {
+	blog := testBlog()
+	blog.CurrentPost = nil
+
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, blog); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := new(OnePayload)
+	if err := json.NewDecoder(out).Decode(resp); err != nil {
+		t.Fatal(err)
+	}
+
+	// a null to-one relationship is written as null data only
+	expected := map[string]interface{}{"data": nil}
+	if actual := resp.Data.Relationships["current_post"]; !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Was expecting %v, got %v", expected, actual)
+	}
+}
+
 func TestSupportsMetable(t *testing.T) {
 	testModel := &Blog{
 		ID:        5,
@@ -816,3 +837,354 @@ func TestMarshal_InvalidIntefaceArgument(t *testing.T) {
 		t.Fatal("Was expecting an error")
 	}
 }
+
+func TestMarshalFields(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, testBlog(), MarshalFields(map[string][]string{
+		"blogs":    {"title", "posts"},
+		"comments": {},
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := new(OnePayload)
+	if err := json.NewDecoder(out).Decode(resp); err != nil {
+		t.Fatal(err)
+	}
+
+	data := resp.Data
+	if e, a := "blogs", data.Type; e != a {
+		t.Fatalf("Was expecting type %q, got %q", e, a)
+	}
+	if e, a := "5", data.ID; e != a {
+		t.Fatalf("Was expecting id %q, got %q", e, a)
+	}
+	if e, a := 1, len(data.Attributes); e != a {
+		t.Fatalf("Was expecting %d attribute, got %v", e, data.Attributes)
+	}
+	if _, ok := data.Attributes["title"]; !ok {
+		t.Fatal("Was expecting the title attribute")
+	}
+	if e, a := 1, len(data.Relationships); e != a {
+		t.Fatalf("Was expecting %d relationship, got %v", e, data.Relationships)
+	}
+	if _, ok := data.Relationships["posts"]; !ok {
+		t.Fatal("Was expecting the posts relationship")
+	}
+
+	for _, n := range resp.Included {
+		switch n.Type {
+		case "posts":
+			if _, ok := n.Attributes["title"]; !ok {
+				t.Fatal("Was expecting posts to be marshaled with all fields")
+			}
+			if _, ok := n.Relationships["comments"]; !ok {
+				t.Fatal("Was expecting posts to be marshaled with all relationships")
+			}
+		case "comments":
+			if n.ID == "" {
+				t.Fatal("Was expecting the comment id to be kept")
+			}
+			if n.Attributes != nil {
+				t.Fatalf("Was expecting no comment attributes, got %v", n.Attributes)
+			}
+		default:
+			t.Fatalf("Unexpected included type %q", n.Type)
+		}
+	}
+}
+
+func TestMarshalFields_excludedRelationIsNotIncluded(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, testBlog(), MarshalFields(map[string][]string{
+		"blogs": {"current_post"},
+		"posts": {"title"},
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := new(OnePayload)
+	if err := json.NewDecoder(out).Decode(resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, a := 1, len(resp.Included); e != a {
+		t.Fatalf("Was expecting %d included resource, got %d", e, a)
+	}
+	post := resp.Included[0]
+	if e, a := "posts", post.Type; e != a {
+		t.Fatalf("Was expecting the included type to be %q, got %q", e, a)
+	}
+	if post.Relationships != nil {
+		t.Fatalf("Was expecting no post relationships, got %v", post.Relationships)
+	}
+}
+
+func TestMarshalFields_embeddedStruct(t *testing.T) {
+	vehicle := &Vehicle{
+		ID:          1,
+		Make:        "VW",
+		Model:       "R32",
+		Engine:      Engine{NumberOfCylinders: 6, HorsePower: 250},
+		BlockHeater: &BlockHeater{Watts: 150},
+	}
+
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, vehicle, MarshalFields(map[string][]string{
+		"car": {"make", "hp"},
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := new(OnePayload)
+	if err := json.NewDecoder(out).Decode(resp); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := attributes{"make": "VW", "hp": float64(250)}
+	if !reflect.DeepEqual(expected, resp.Data.Attributes) {
+		t.Fatalf("Was expecting attributes %v, got %v", expected, resp.Data.Attributes)
+	}
+}
+
+func TestMarshalInclude(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, testBlog(), MarshalInclude("current_post.latest_comment")); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := new(OnePayload)
+	if err := json.NewDecoder(out).Decode(resp); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{}
+	for _, n := range resp.Included {
+		keys = append(keys, n.Type+","+n.ID)
+	}
+	sort.Strings(keys)
+	if e, a := []string{"comments,1", "posts,1"}, keys; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting included %v, got %v", e, a)
+	}
+
+	// relationships off the include paths are still written as linkage
+	posts := resp.Data.Relationships["posts"].(map[string]interface{})["data"].([]interface{})
+	if e, a := 2, len(posts); e != a {
+		t.Fatalf("Was expecting %d posts in the linkage, got %d", e, a)
+	}
+}
+
+func TestMarshalInclude_commaSeparated(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, []*Blog{testBlog()}, MarshalInclude("posts,current_post")); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := new(ManyPayload)
+	if err := json.NewDecoder(out).Decode(resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, a := 2, len(resp.Included); e != a {
+		t.Fatalf("Was expecting %d included resources, got %d", e, a)
+	}
+	for _, n := range resp.Included {
+		if n.Type != "posts" {
+			t.Fatalf("Was expecting only posts to be included, got %q", n.Type)
+		}
+	}
+}
+
+func TestMarshalInclude_unknownPath(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	err := MarshalPayload(out, testBlog(), MarshalInclude("posts.author"))
+	if err == nil {
+		t.Fatal("Was expecting an error")
+	}
+
+	errObj, ok := err.(*ErrorObject)
+	if !ok {
+		t.Fatalf("Was expecting an *ErrorObject, got %T", err)
+	}
+	if e, a := "400", errObj.Status; e != a {
+		t.Fatalf("Was expecting status %q, got %q", e, a)
+	}
+	if out.Len() != 0 {
+		t.Fatal("Was expecting nothing to be written")
+	}
+}
+
+func TestMarshalPayload_includedOrderIsStable(t *testing.T) {
+	blogs := []*Blog{testBlog(), testBlog()}
+
+	expected := bytes.NewBuffer(nil)
+	if err := MarshalPayload(expected, blogs); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		out := bytes.NewBuffer(nil)
+		if err := MarshalPayload(out, blogs); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(expected.Bytes(), out.Bytes()) {
+			t.Fatalf("Expected:\n%s\nto equal:\n%s", out.Bytes(), expected.Bytes())
+		}
+	}
+
+	resp := new(ManyPayload)
+	if err := json.NewDecoder(expected).Decode(resp); err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for _, n := range resp.Included {
+		keys = append(keys, n.Type+","+n.ID)
+	}
+	if e, a := []string{"posts,1", "comments,1", "comments,2", "posts,2", "comments,3"}, keys; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting included in first-encounter order %v, got %v", e, a)
+	}
+}
+
+func TestMarshalSortIncluded(t *testing.T) {
+	blog := testBlog()
+	blog.Posts = append(blog.Posts, &Post{ID: 10}, &Post{ID: 9})
+
+	payload, err := Marshal(blog, MarshalSortIncluded())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{}
+	for _, n := range payload.(*OnePayload).Included {
+		keys = append(keys, n.Type+","+n.ID)
+	}
+	expected := []string{"comments,1", "comments,2", "comments,3", "posts,1", "posts,2", "posts,9", "posts,10"}
+	if !reflect.DeepEqual(expected, keys) {
+		t.Fatalf("Was expecting included sorted as %v, got %v", expected, keys)
+	}
+}
+
+func TestMarshalTopLevelMembers_one(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, &Book{ID: 1},
+		MarshalLinks(Links{"self": "https://example.com/api/books/1"}),
+		MarshalMeta(Meta{"copyright": "Google"}),
+		MarshalJSONAPI(JSONAPIObject{Version: "1.1", Profile: []string{"https://example.com/profile"}}),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	var jsonData map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &jsonData); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"links": map[string]interface{}{"self": "https://example.com/api/books/1"},
+		"meta":  map[string]interface{}{"copyright": "Google"},
+		"jsonapi": map[string]interface{}{
+			"version": "1.1",
+			"profile": []interface{}{"https://example.com/profile"},
+		},
+	}
+	for k, v := range expected {
+		if !reflect.DeepEqual(v, jsonData[k]) {
+			t.Fatalf("Was expecting %s to be %v, got %v", k, v, jsonData[k])
+		}
+	}
+}
+
+func TestMarshalTopLevelMembers_many(t *testing.T) {
+	payload, err := Marshal(Blogs{testBlog()},
+		MarshalLinks(Links{KeyNextPage: "https://example.com/api/blogs?page[number]=2"}),
+		MarshalMeta(Meta{"total": 1}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	many := payload.(*ManyPayload)
+
+	// the links and meta of the Linkable and Metable slice are kept
+	links := *many.Links
+	if _, ok := links["self"]; !ok {
+		t.Fatal("Was expecting the self link of the slice to be kept")
+	}
+	if e, a := "https://example.com/api/blogs?page[number]=2", links[KeyNextPage]; e != a {
+		t.Fatalf("Was expecting the next link %q, got %q", e, a)
+	}
+	meta := *many.Meta
+	if _, ok := meta["total"]; !ok {
+		t.Fatal("Was expecting the total meta")
+	}
+	if _, ok := meta["query"]; !ok {
+		t.Fatal("Was expecting the meta of the slice to be kept")
+	}
+}
+
+func TestMarshalLinks_invalid(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	if err := MarshalPayload(out, &Book{ID: 1},
+		MarshalLinks(Links{"self": 1}),
+	); err == nil {
+		t.Fatal("Was expecting an error")
+	}
+}
+
+func TestMarshalPayload_lid(t *testing.T) {
+	article := &Article{
+		Lid:          "a1",
+		Title:        "New",
+		Author:       &Person{Lid: "p1", Name: "Ann"},
+		Contributors: []*Person{{Lid: "p1", Name: "Ann"}, {Lid: "p2", Name: "Bob"}},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, article); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"type":       "articles",
+			"lid":        "a1",
+			"attributes": map[string]interface{}{"title": "New"},
+			"relationships": map[string]interface{}{
+				"author": map[string]interface{}{
+					"data": map[string]interface{}{"type": "people", "lid": "p1"},
+				},
+				"contributors": map[string]interface{}{
+					"data": []interface{}{
+						map[string]interface{}{"type": "people", "lid": "p1"},
+						map[string]interface{}{"type": "people", "lid": "p2"},
+					},
+				},
+			},
+		},
+		"included": []interface{}{
+			map[string]interface{}{
+				"type":       "people",
+				"lid":        "p1",
+				"attributes": map[string]interface{}{"name": "Ann"},
+			},
+			map[string]interface{}{
+				"type":       "people",
+				"lid":        "p2",
+				"attributes": map[string]interface{}{"name": "Bob"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := isJSONEqual(expected, buf.Bytes()); err != nil || !ok {
+		t.Fatalf("Was expecting %s, got %s", expected, buf.Bytes())
+	}
+
+	out := new(Article)
+	if err := UnmarshalPayload(buf, out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(article, out) {
+		t.Fatalf("Was expecting %#v, got %#v", article, out)
+	}
+}
@@ -0,0 +1,232 @@
+package jsonapi
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// modelSchema is the parsed form of the jsonapi annotations of a struct type.
+// It is built once per type by schemaOf, and shared by marshaling and
+// unmarshaling so that struct tags are not parsed on every call.
+type modelSchema struct {
+	// embeddeds holds the embedded structs and pointers to embedded structs.
+	embeddeds []embeddedField
+	// fields holds the other annotated fields, in declaration order.
+	fields []annotatedField
+
+	// primary is the JSON API type of the struct; see primaryTypeName.
+	primary string
+	// attrs and rels map the attribute and relationship names declared by the
+	// struct and its embedded structs to their struct fields.
+	attrs, rels map[string]fieldPath
+	// relTypes maps relationship names to the struct type of the related
+	// resources, or to the interface type of a polymorphic relationship; see
+	// relationFieldType.
+	relTypes map[string]reflect.Type
+}
+
+// embeddedField is an embedded struct, or pointer to an embedded struct.
+type embeddedField struct {
+	index int
+	name  string
+	ptr   bool
+	// typ is the struct type, i.e. the element type of a pointer.
+	typ reflect.Type
+}
+
+// fieldPath locates the struct field of an attribute or relationship, which
+// may be a field of an embedded struct.
+type fieldPath struct {
+	// name is the path of field names, e.g. "Engine.HorsePower".
+	name string
+	// index is the index sequence of the field, as given to FieldByIndex.
+	index []int
+}
+
+// embed returns the path of the field p of the embedded struct em.
+func (p fieldPath) embed(em embeddedField) fieldPath {
+	return fieldPath{
+		name:  em.name + "." + p.name,
+		index: append([]int{em.index}, p.index...),
+	}
+}
+
+// annotatedField is a struct field with a jsonapi annotation.
+type annotatedField struct {
+	index int
+	field reflect.StructField
+	// args holds the comma-separated parts of the annotation.
+	args       []string
+	annotation string
+	// name is the JSON API type of a primary field, or the member name of an
+	// attribute or relationship; it is empty if the annotation has no name.
+	name      string
+	omitEmpty bool
+	iso8601   bool
+	// kind is the kind of the field's type, after dereferencing a pointer.
+	kind reflect.Kind
+}
+
+var schemaCache = struct {
+	sync.RWMutex
+	schemas map[reflect.Type]*modelSchema
+}{schemas: map[reflect.Type]*modelSchema{}}
+
+// schemaOf returns the schema of the struct type t.
+func schemaOf(t reflect.Type) *modelSchema {
+	schemaCache.RLock()
+	s, ok := schemaCache.schemas[t]
+	schemaCache.RUnlock()
+	if ok {
+		return s
+	}
+
+	s = buildSchema(t)
+
+	schemaCache.Lock()
+	if cached, ok := schemaCache.schemas[t]; ok {
+		s = cached
+	} else {
+		schemaCache.schemas[t] = s
+	}
+	schemaCache.Unlock()
+	return s
+}
+
+func buildSchema(t reflect.Type) *modelSchema {
+	s := &modelSchema{
+		attrs:    map[string]fieldPath{},
+		rels:     map[string]fieldPath{},
+		relTypes: map[string]reflect.Type{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get(annotationJSONAPI)
+
+		if shouldIgnoreField(tag) {
+			continue
+		}
+
+		if isEmbeddedStruct(field) {
+			s.embeddeds = append(s.embeddeds, embeddedField{index: i, name: field.Name, typ: field.Type})
+			continue
+		}
+		if isEmbeddedStructPtr(field) {
+			s.embeddeds = append(s.embeddeds, embeddedField{index: i, name: field.Name, ptr: true, typ: field.Type.Elem()})
+			continue
+		}
+
+		if tag == "" {
+			continue
+		}
+
+		args := strings.Split(tag, annotationSeperator)
+		f := annotatedField{
+			index:      i,
+			field:      field,
+			args:       args,
+			annotation: args[0],
+			kind:       field.Type.Kind(),
+		}
+		if f.kind == reflect.Ptr {
+			f.kind = field.Type.Elem().Kind()
+		}
+		if len(args) > 1 {
+			f.name = args[1]
+		}
+		if len(args) > 2 {
+			for _, arg := range args[2:] {
+				switch arg {
+				case annotationOmitEmpty:
+					f.omitEmpty = true
+				case annotationISO8601:
+					f.iso8601 = true
+				}
+			}
+		}
+		s.fields = append(s.fields, f)
+
+		if f.name == "" {
+			continue
+		}
+		switch f.annotation {
+		case annotationPrimary:
+			if s.primary == "" {
+				s.primary = f.name
+			}
+		case annotationAttribute:
+			if _, ok := s.attrs[f.name]; !ok {
+				s.attrs[f.name] = fieldPath{name: field.Name, index: []int{i}}
+			}
+		case annotationRelation:
+			if _, ok := s.rels[f.name]; !ok {
+				s.rels[f.name] = fieldPath{name: field.Name, index: []int{i}}
+			}
+			if _, ok := s.relTypes[f.name]; !ok {
+				relType := field.Type
+				for relType.Kind() == reflect.Slice || relType.Kind() == reflect.Ptr {
+					relType = relType.Elem()
+				}
+				if relType.Kind() != reflect.Struct && relType.Kind() != reflect.Interface {
+					relType = nil
+				}
+				s.relTypes[f.name] = relType
+			}
+		}
+	}
+
+	// the fields of t take precedence over those of its embedded structs
+	var embeddedPrimary string
+	for _, em := range s.embeddeds {
+		emSchema := schemaOf(em.typ)
+		if emSchema.primary != "" {
+			embeddedPrimary = emSchema.primary
+		}
+		for name, path := range emSchema.attrs {
+			if _, ok := s.attrs[name]; !ok {
+				s.attrs[name] = path.embed(em)
+			}
+		}
+		for name, path := range emSchema.rels {
+			if _, ok := s.rels[name]; !ok {
+				s.rels[name] = path.embed(em)
+			}
+		}
+		for name, relType := range emSchema.relTypes {
+			if _, ok := s.relTypes[name]; !ok && relType != nil {
+				s.relTypes[name] = relType
+			}
+		}
+	}
+	if s.primary == "" {
+		s.primary = embeddedPrimary
+	}
+
+	return s
+}
+
+// fieldByPath returns the field of the struct value v at path. Nil pointers
+// to embedded structs along the path are allocated if alloc is true; otherwise
+// the zero Value is returned for them.
+func fieldByPath(v reflect.Value, path fieldPath, alloc bool) reflect.Value {
+	for i, index := range path.index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(index)
+	}
+	return v
+}
+
+// fieldTypeByPath returns the type of the field of the struct type t at path.
+func fieldTypeByPath(t reflect.Type, path fieldPath) reflect.Type {
+	return t.FieldByIndex(path.index).Type
+}
@@ -0,0 +1,170 @@
+package jsonapi
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSchemaOf(t *testing.T) {
+	vehicleType := reflect.TypeOf(Vehicle{})
+
+	var wg sync.WaitGroup
+	schemas := make([]*modelSchema, 10)
+	for i := range schemas {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			schemas[i] = schemaOf(vehicleType)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, s := range schemas[1:] {
+		if s != schemas[0] {
+			t.Fatal("Was expecting the schema to be cached")
+		}
+	}
+
+	s := schemas[0]
+	if s.primary != "car" {
+		t.Fatalf("Was expecting the primary type car, got %q", s.primary)
+	}
+	if len(s.embeddeds) != 2 || s.embeddeds[0].ptr || !s.embeddeds[1].ptr {
+		t.Fatalf("Was expecting Engine and *BlockHeater to be embedded, got %#v", s.embeddeds)
+	}
+	expected := map[string]string{
+		"make":      "Make",
+		"model":     "Model",
+		"year":      "Year",
+		"cylinders": "Engine.NumberOfCylinders",
+		"hp":        "Engine.HorsePower",
+		"watts":     "BlockHeater.Watts",
+	}
+	attrs := map[string]string{}
+	for name, path := range s.attrs {
+		attrs[name] = path.name
+		field, _ := vehicleType.FieldByName(path.name[strings.LastIndex(path.name, ".")+1:])
+		if e, a := field.Index, path.index; !reflect.DeepEqual(e, a) {
+			t.Fatalf("Was expecting the index %v for %q, got %v", e, name, a)
+		}
+	}
+	if !reflect.DeepEqual(expected, attrs) {
+		t.Fatalf("Was expecting the attributes %v, got %v", expected, attrs)
+	}
+}
+
+func TestSchemaOf_relationTypes(t *testing.T) {
+	s := schemaOf(reflect.TypeOf(Post{}))
+
+	if relType, ok := s.relTypes["comments"]; !ok || relType != reflect.TypeOf(Comment{}) {
+		t.Fatalf("Was expecting comments to relate to Comment, got %v", relType)
+	}
+	// declared by the embedded Blog
+	if relType, ok := s.relTypes["posts"]; !ok || relType != reflect.TypeOf(Post{}) {
+		t.Fatalf("Was expecting posts to relate to Post, got %v", relType)
+	}
+	if s.primary != "posts" {
+		t.Fatalf("Was expecting the annotation of Post to take precedence, got %q", s.primary)
+	}
+}
+
+func benchmarkBooks() []interface{} {
+	books := make([]interface{}, 1000)
+	for i := range books {
+		description, pages := "A book", uint(100)
+		books[i] = &Book{
+			ID:          uint64(i + 1),
+			Author:      "Author",
+			ISBN:        "1234",
+			Title:       "Title",
+			Description: &description,
+			Pages:       &pages,
+			Tags:        []string{"a", "b"},
+		}
+	}
+	return books
+}
+
+func benchmarkVehicles() []interface{} {
+	vehicles := make([]interface{}, 1000)
+	for i := range vehicles {
+		vehicles[i] = &Vehicle{
+			ID:          uint(i + 1),
+			Make:        "VW",
+			Model:       "R32",
+			Year:        2008,
+			Engine:      Engine{NumberOfCylinders: 6, HorsePower: 250},
+			BlockHeater: &BlockHeater{Watts: 150},
+		}
+	}
+	return vehicles
+}
+
+func BenchmarkSchemaOf_cached(b *testing.B) {
+	t := reflect.TypeOf(Vehicle{})
+	schemaOf(t)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		schemaOf(t)
+	}
+}
+
+// BenchmarkSchemaOf_uncached parses the annotations of a type on every call,
+// as marshaling and unmarshaling did for every model before the cache; compare
+// it with BenchmarkSchemaOf_cached.
+func BenchmarkSchemaOf_uncached(b *testing.B) {
+	t := reflect.TypeOf(Vehicle{})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buildSchema(t)
+	}
+}
+
+func benchmarkMarshal(b *testing.B, models []interface{}) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := MarshalPayload(ioutil.Discard, models); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkUnmarshal(b *testing.B, models []interface{}) {
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, models); err != nil {
+		b.Fatal(err)
+	}
+	in := buf.Bytes()
+	t := reflect.TypeOf(models[0])
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := UnmarshalManyPayload(bytes.NewReader(in), t); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalMany_books(b *testing.B) {
+	benchmarkMarshal(b, benchmarkBooks())
+}
+
+func BenchmarkMarshalMany_embeddedStructs(b *testing.B) {
+	benchmarkMarshal(b, benchmarkVehicles())
+}
+
+func BenchmarkUnmarshalMany_books(b *testing.B) {
+	benchmarkUnmarshal(b, benchmarkBooks())
+}
+
+func BenchmarkUnmarshalMany_embeddedStructs(b *testing.B) {
+	benchmarkUnmarshal(b, benchmarkVehicles())
+}
@@ -0,0 +1,588 @@
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrResourceNotFound may be returned by the methods of a resource registered
+// with a Server for a resource that does not exist; the Server responds with
+// 404 Not Found.
+var ErrResourceNotFound = errors.New("resource not found")
+
+// Lister lists the resources of a type, for GET /blogs. List returns a slice
+// of struct pointers, which may be empty but not nil.
+type Lister interface {
+	List(ctx context.Context, q *Query) (interface{}, error)
+}
+
+// Finder finds a resource by id, for GET /blogs/{id}, as well as
+// /blogs/{id}/posts and /blogs/{id}/relationships/posts. Find returns a struct
+// pointer, or ErrResourceNotFound.
+type Finder interface {
+	Find(ctx context.Context, id string, q *Query) (interface{}, error)
+}
+
+// Creator creates a resource, for POST /blogs. Create is given the model
+// unmarshaled from the request, and returns the created model, which is
+// written with 201 Created, or nil to respond with 204 No Content.
+type Creator interface {
+	Create(ctx context.Context, model interface{}) (interface{}, error)
+}
+
+// Updater updates a resource, for PATCH /blogs/{id} as well as
+// PATCH /blogs/{id}/relationships/posts. Update is given the model
+// unmarshaled from the request, and the Presence of its members, which are
+// the only ones to update. It returns the updated model, or nil to respond
+// with 204 No Content.
+type Updater interface {
+	Update(ctx context.Context, model interface{}, fields *Presence) (interface{}, error)
+}
+
+// Deleter deletes a resource by id, for DELETE /blogs/{id}.
+type Deleter interface {
+	Delete(ctx context.Context, id string) error
+}
+
+// RelationshipAdder adds members to a to-many relationship, for
+// POST /blogs/{id}/relationships/posts. AddToRelationship is given the id of
+// the resource, the name of the relationship, and a slice of the related
+// models unmarshaled from the request, with only their ids set; members it
+// already has are to be left as they are. It returns the updated model, whose
+// relationship is written, or nil to respond with 204 No Content.
+type RelationshipAdder interface {
+	AddToRelationship(ctx context.Context, id, name string, related interface{}) (interface{}, error)
+}
+
+// RelationshipRemover removes members from a to-many relationship, for
+// DELETE /blogs/{id}/relationships/posts. RemoveFromRelationship is given
+// the same arguments as AddToRelationship, and returns the same results.
+type RelationshipRemover interface {
+	RemoveFromRelationship(ctx context.Context, id, name string, related interface{}) (interface{}, error)
+}
+
+// Server is an http.Handler that serves the resources registered with it:
+//
+//	srv := jsonapi.NewServer()
+//	if err := srv.Register(new(Blog), blogStore); err != nil {
+//		log.Fatal(err)
+//	}
+//	http.Handle("/", srv)
+//
+// For the type "blogs", it routes
+//
+//	GET    /blogs                           Lister
+//	POST   /blogs                           Creator
+//	GET    /blogs/{id}                      Finder
+//	PATCH  /blogs/{id}                      Updater
+//	DELETE /blogs/{id}                      Deleter
+//	GET    /blogs/{id}/posts                Finder, with the related resources
+//	GET    /blogs/{id}/relationships/posts  Finder, with the resource linkage
+//	PATCH  /blogs/{id}/relationships/posts  Finder and Updater
+//	POST   /blogs/{id}/relationships/posts  RelationshipAdder
+//	DELETE /blogs/{id}/relationships/posts  RelationshipRemover
+//
+// Requests are content negotiated as by Negotiate, and responses have the
+// negotiated media type as their Content-Type. Query parameters are parsed by
+// ParseRequest; include paths and sparse fieldsets are applied to the
+// response. Request documents are unmarshaled with UnmarshalPartialPayload.
+// Errors returned by the resources are written as errors documents:
+// ErrorObjects, *ErrorObject and *UnmarshalError as they are,
+// ErrResourceNotFound as 404 Not Found, and any other error as 500 Internal
+// Server Error. Use http.StripPrefix to serve the resources under a path
+// prefix.
+type Server struct {
+	handler   http.Handler
+	resources map[string]*serverResource
+}
+
+// serverResource is a resource type registered with a Server.
+type serverResource struct {
+	typ     reflect.Type
+	handler interface{}
+}
+
+// NewServer returns a Server with no resources; the NegotiateOptions give the
+// extensions and profiles it supports.
+func NewServer(opts ...NegotiateOption) *Server {
+	s := &Server{resources: map[string]*serverResource{}}
+	s.handler = Negotiate(http.HandlerFunc(s.route), opts...)
+	return s
+}
+
+// Register serves the resources of the type of model, a struct pointer, with
+// handler, which implements one or more of Lister, Finder, Creator, Updater,
+// Deleter, RelationshipAdder and RelationshipRemover. Requests for a method
+// that handler does not implement get a 405 Method Not Allowed response.
+func (s *Server) Register(model interface{}, handler interface{}) error {
+	t := reflect.TypeOf(model)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return ErrUnexpectedType
+	}
+	t = t.Elem()
+
+	name := primaryTypeName(t)
+	if name == "" {
+		return fmt.Errorf("%v has no primary annotation", t)
+	}
+	if _, ok := s.resources[name]; ok {
+		return fmt.Errorf("type %q is already registered", name)
+	}
+
+	switch handler.(type) {
+	case Lister, Finder, Creator, Updater, Deleter, RelationshipAdder, RelationshipRemover:
+	default:
+		return fmt.Errorf("%T implements none of the Server's resource interfaces", handler)
+	}
+
+	s.resources[name] = &serverResource{typ: t, handler: handler}
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(headerContentType, NegotiationFromContext(r.Context()).MediaType())
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	res, ok := s.resources[segments[0]]
+	if !ok || len(segments) > 4 || (len(segments) == 4 && segments[2] != "relationships") {
+		writeServerError(w, ErrResourceNotFound)
+		return
+	}
+
+	q, errs := ParseRequest(r)
+	if len(errs) > 0 {
+		WriteErrors(w, errs)
+		return
+	}
+
+	switch len(segments) {
+	case 1:
+		switch r.Method {
+		case http.MethodGet:
+			if lister, ok := res.handler.(Lister); ok {
+				res.list(w, r, lister, q)
+				return
+			}
+		case http.MethodPost:
+			if creator, ok := res.handler.(Creator); ok {
+				res.create(w, r, creator, q)
+				return
+			}
+		}
+		methodNotAllowed(w, map[string]bool{
+			http.MethodGet:  isLister(res.handler),
+			http.MethodPost: isCreator(res.handler),
+		})
+	case 2:
+		id := segments[1]
+		switch r.Method {
+		case http.MethodGet:
+			if finder, ok := res.handler.(Finder); ok {
+				res.find(w, r, finder, id, q)
+				return
+			}
+		case http.MethodPatch:
+			if updater, ok := res.handler.(Updater); ok {
+				res.update(w, r, updater, id, q)
+				return
+			}
+		case http.MethodDelete:
+			if deleter, ok := res.handler.(Deleter); ok {
+				res.delete(w, r, deleter, id)
+				return
+			}
+		}
+		methodNotAllowed(w, map[string]bool{
+			http.MethodGet:    isFinder(res.handler),
+			http.MethodPatch:  isUpdater(res.handler),
+			http.MethodDelete: isDeleter(res.handler),
+		})
+	case 3:
+		id, name := segments[1], segments[2]
+		if finder, ok := res.handler.(Finder); ok && r.Method == http.MethodGet {
+			res.related(w, r, finder, id, name, q)
+			return
+		}
+		methodNotAllowed(w, map[string]bool{
+			http.MethodGet: isFinder(res.handler),
+		})
+	case 4:
+		id, name := segments[1], segments[3]
+		finder, isFinder := res.handler.(Finder)
+		updater, isUpdater := res.handler.(Updater)
+		adder, isAdder := res.handler.(RelationshipAdder)
+		remover, isRemover := res.handler.(RelationshipRemover)
+		switch {
+		case isFinder && r.Method == http.MethodGet:
+			res.relationship(w, r, finder, id, name, q)
+			return
+		case isFinder && isUpdater && r.Method == http.MethodPatch:
+			res.updateRelationship(w, r, finder, updater, id, name)
+			return
+		case isAdder && r.Method == http.MethodPost:
+			res.changeRelationship(w, r, adder.AddToRelationship, id, name)
+			return
+		case isRemover && r.Method == http.MethodDelete:
+			res.changeRelationship(w, r, remover.RemoveFromRelationship, id, name)
+			return
+		}
+		methodNotAllowed(w, map[string]bool{
+			http.MethodGet:    isFinder,
+			http.MethodPatch:  isFinder && isUpdater,
+			http.MethodPost:   isAdder,
+			http.MethodDelete: isRemover,
+		})
+	}
+}
+
+func (res *serverResource) list(w http.ResponseWriter, r *http.Request, lister Lister, q *Query) {
+	models, err := lister.List(r.Context(), q)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	WriteResource(w, http.StatusOK, models, q.MarshalOptions()...)
+}
+
+func (res *serverResource) find(w http.ResponseWriter, r *http.Request, finder Finder, id string, q *Query) {
+	model, err := res.findModel(r, finder, id, q)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	WriteResource(w, http.StatusOK, model, q.MarshalOptions()...)
+}
+
+func (res *serverResource) create(w http.ResponseWriter, r *http.Request, creator Creator, q *Query) {
+	model, _, err := res.unmarshal(r, "")
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+
+	created, err := creator.Create(r.Context(), model)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if isNilModel(created) {
+		writeNoContent(w)
+		return
+	}
+	WriteResource(w, http.StatusCreated, created, q.MarshalOptions()...)
+}
+
+func (res *serverResource) update(w http.ResponseWriter, r *http.Request, updater Updater, id string, q *Query) {
+	model, fields, err := res.unmarshal(r, id)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+
+	updated, err := updater.Update(r.Context(), model, fields)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if isNilModel(updated) {
+		writeNoContent(w)
+		return
+	}
+	WriteResource(w, http.StatusOK, updated, q.MarshalOptions()...)
+}
+
+func (res *serverResource) delete(w http.ResponseWriter, r *http.Request, deleter Deleter, id string) {
+	if err := deleter.Delete(r.Context(), id); err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeNoContent(w)
+}
+
+// related writes the related resources of the relationship name.
+func (res *serverResource) related(w http.ResponseWriter, r *http.Request, finder Finder, id, name string, q *Query) {
+	path, ok := schemaOf(res.typ).rels[name]
+	if !ok {
+		writeServerError(w, ErrResourceNotFound)
+		return
+	}
+	model, err := res.findModel(r, finder, id, nil)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+
+	related := fieldByPath(reflect.ValueOf(model).Elem(), path, false)
+	if related.IsValid() && related.Kind() == reflect.Interface && !related.IsNil() {
+		related = related.Elem()
+	}
+	switch {
+	case !related.IsValid() || (related.Kind() == reflect.Slice && related.IsNil()):
+		if fieldTypeByPath(res.typ, path).Kind() == reflect.Slice {
+			writeDocument(w, http.StatusOK, &ManyPayload{Data: []*Node{}})
+			return
+		}
+		writeDocument(w, http.StatusOK, &OnePayload{})
+	case related.Kind() != reflect.Slice && related.IsNil():
+		writeDocument(w, http.StatusOK, &OnePayload{})
+	default:
+		WriteResource(w, http.StatusOK, related.Interface(), q.MarshalOptions()...)
+	}
+}
+
+// relationship writes the resource linkage of the relationship name.
+func (res *serverResource) relationship(w http.ResponseWriter, r *http.Request, finder Finder, id, name string, q *Query) {
+	if _, ok := schemaOf(res.typ).rels[name]; !ok {
+		writeServerError(w, ErrResourceNotFound)
+		return
+	}
+	model, err := res.findModel(r, finder, id, q)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	res.writeRelationship(w, model, name)
+}
+
+// updateRelationship replaces the relationship name of the resource with the
+// linkage of the request document.
+func (res *serverResource) updateRelationship(w http.ResponseWriter, r *http.Request, finder Finder, updater Updater, id, name string) {
+	path, ok := schemaOf(res.typ).rels[name]
+	if !ok {
+		writeServerError(w, ErrResourceNotFound)
+		return
+	}
+	model, err := res.findModel(r, finder, id, nil)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+
+	if err := UnmarshalRelationship(r.Body, model, name, UnmarshalErrorPointers()); err != nil {
+		writeServerError(w, requestDocumentError(err))
+		return
+	}
+
+	fields := &Presence{
+		Attributes:    map[string]string{},
+		Relationships: map[string]string{name: path.name},
+	}
+	updated, err := updater.Update(r.Context(), model, fields)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if isNilModel(updated) {
+		writeNoContent(w)
+		return
+	}
+	res.writeRelationship(w, updated, name)
+}
+
+// changeRelationship adds or removes the linkage of the request document to or
+// from the to-many relationship name, with change.
+func (res *serverResource) changeRelationship(w http.ResponseWriter, r *http.Request,
+	change func(ctx context.Context, id, name string, related interface{}) (interface{}, error), id, name string) {
+	path, ok := schemaOf(res.typ).rels[name]
+	if !ok {
+		writeServerError(w, ErrResourceNotFound)
+		return
+	}
+	if fieldTypeByPath(res.typ, path).Kind() != reflect.Slice {
+		writeServerError(w, &ErrorObject{
+			Title:  http.StatusText(http.StatusForbidden),
+			Detail: fmt.Sprintf("Members can only be added to or removed from a to-many relationship, and %q is not one", name),
+			Status: "403",
+		})
+		return
+	}
+
+	model := reflect.New(res.typ)
+	if err := UnmarshalRelationship(r.Body, model.Interface(), name, UnmarshalErrorPointers()); err != nil {
+		writeServerError(w, requestDocumentError(err))
+		return
+	}
+
+	related := fieldByPath(model.Elem(), path, true).Interface()
+	updated, err := change(r.Context(), id, name, related)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if isNilModel(updated) {
+		writeNoContent(w)
+		return
+	}
+	res.writeRelationship(w, updated, name)
+}
+
+func (res *serverResource) writeRelationship(w http.ResponseWriter, model interface{}, name string) {
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalRelationship(buf, model, name); err != nil {
+		writeInternalError(w)
+		return
+	}
+	setContentType(w)
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+// findModel finds the resource id, which must exist.
+func (res *serverResource) findModel(r *http.Request, finder Finder, id string, q *Query) (interface{}, error) {
+	if q == nil {
+		q = &Query{}
+	}
+	model, err := finder.Find(r.Context(), id, q)
+	if err != nil {
+		return nil, err
+	}
+	if isNilModel(model) {
+		return nil, ErrResourceNotFound
+	}
+	return model, nil
+}
+
+// unmarshal unmarshals the request document into a new model. The resource
+// must have the type of res, and the given id, if any.
+func (res *serverResource) unmarshal(r *http.Request, id string) (interface{}, *Presence, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc := struct {
+		Data *struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, nil, requestDocumentError(err)
+	}
+	if doc.Data == nil {
+		return nil, nil, newRequestError("400", "/data", "The request document must have a resource object as data")
+	}
+	if name := primaryTypeName(res.typ); doc.Data.Type != name {
+		return nil, nil, newRequestError("409", "/data/type",
+			fmt.Sprintf("The resource must be of type %q, got %q", name, doc.Data.Type))
+	}
+	if id != "" && doc.Data.ID == "" {
+		return nil, nil, newRequestError("400", "/data/id", "The resource must have an id")
+	}
+	if id != "" && doc.Data.ID != id {
+		return nil, nil, newRequestError("409", "/data/id",
+			fmt.Sprintf("The resource must have the id %q, got %q", id, doc.Data.ID))
+	}
+
+	model := reflect.New(res.typ).Interface()
+	fields, err := UnmarshalPartialPayload(bytes.NewReader(body), model, UnmarshalErrorPointers())
+	if err != nil {
+		return nil, nil, requestDocumentError(err)
+	}
+	return model, fields, nil
+}
+
+// methodNotAllowed writes a 405 response, with the methods that are allowed.
+func methodNotAllowed(w http.ResponseWriter, methods map[string]bool) {
+	var allowed []string
+	for method, ok := range methods {
+		if ok {
+			allowed = append(allowed, method)
+		}
+	}
+	sort.Strings(allowed)
+
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	WriteErrors(w, []*ErrorObject{{
+		Title:  http.StatusText(http.StatusMethodNotAllowed),
+		Status: "405",
+	}})
+}
+
+func isLister(handler interface{}) bool  { _, ok := handler.(Lister); return ok }
+func isFinder(handler interface{}) bool  { _, ok := handler.(Finder); return ok }
+func isCreator(handler interface{}) bool { _, ok := handler.(Creator); return ok }
+func isUpdater(handler interface{}) bool { _, ok := handler.(Updater); return ok }
+func isDeleter(handler interface{}) bool { _, ok := handler.(Deleter); return ok }
+
+// isNilModel reports whether model is nil or a nil pointer.
+func isNilModel(model interface{}) bool {
+	if model == nil {
+		return true
+	}
+	v := reflect.ValueOf(model)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// requestDocumentError returns the error to respond with for a request
+// document that could not be unmarshaled.
+func requestDocumentError(err error) error {
+	switch err.(type) {
+	case *UnmarshalError, ErrorObjects, *ErrorObject:
+		return err
+	}
+	return newRequestError("400", "", err.Error())
+}
+
+func newRequestError(status, pointer, detail string) *ErrorObject {
+	e := &ErrorObject{
+		Title:  "Invalid request document",
+		Detail: detail,
+		Status: status,
+	}
+	if pointer != "" {
+		e.Source = &ErrorSource{Pointer: pointer}
+	}
+	return e
+}
+
+// writeServerError writes err, as returned by a resource or the Server, as an
+// errors document.
+func writeServerError(w http.ResponseWriter, err error) {
+	switch e := err.(type) {
+	case ErrorObjects:
+		WriteErrors(w, e)
+	case *ErrorObject:
+		WriteErrors(w, []*ErrorObject{e})
+	case *UnmarshalError:
+		WriteErrors(w, []*ErrorObject{e.ErrorObject()})
+	default:
+		if err == ErrResourceNotFound {
+			WriteErrors(w, []*ErrorObject{{
+				Title:  http.StatusText(http.StatusNotFound),
+				Status: "404",
+			}})
+			return
+		}
+		WriteErrors(w, []*ErrorObject{{
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: "500",
+		}})
+	}
+}
+
+// writeDocument writes a payload that needs no marshaling.
+func writeDocument(w http.ResponseWriter, status int, payload Payloader) {
+	setContentType(w)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// writeNoContent writes a 204 response, which has no Content-Type.
+func writeNoContent(w http.ResponseWriter) {
+	w.Header().Del(headerContentType)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,425 @@
+package jsonapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// blogStore is an in-memory store of blogs implementing all of the Server's
+// resource interfaces.
+type blogStore struct {
+	blogs map[string]*Blog
+}
+
+func (s *blogStore) List(ctx context.Context, q *Query) (interface{}, error) {
+	blogs := []*Blog{}
+	for _, id := range []string{"1", "2"} {
+		if blog, ok := s.blogs[id]; ok {
+			blogs = append(blogs, blog)
+		}
+	}
+	return blogs, nil
+}
+
+func (s *blogStore) Find(ctx context.Context, id string, q *Query) (interface{}, error) {
+	blog, ok := s.blogs[id]
+	if !ok {
+		return nil, ErrResourceNotFound
+	}
+	return blog, nil
+}
+
+func (s *blogStore) Create(ctx context.Context, model interface{}) (interface{}, error) {
+	blog := model.(*Blog)
+	blog.ID = 3
+	s.blogs["3"] = blog
+	return blog, nil
+}
+
+func (s *blogStore) Update(ctx context.Context, model interface{}, fields *Presence) (interface{}, error) {
+	update := model.(*Blog)
+	blog := s.blogs[strconv.Itoa(update.ID)]
+	if fields.HasAttribute("title") {
+		blog.Title = update.Title
+	}
+	if fields.HasRelationship("posts") {
+		blog.Posts = update.Posts
+	}
+	return blog, nil
+}
+
+func (s *blogStore) Delete(ctx context.Context, id string) error {
+	if _, ok := s.blogs[id]; !ok {
+		return ErrResourceNotFound
+	}
+	delete(s.blogs, id)
+	return nil
+}
+
+func (s *blogStore) AddToRelationship(ctx context.Context, id, name string, related interface{}) (interface{}, error) {
+	blog, ok := s.blogs[id]
+	if !ok {
+		return nil, ErrResourceNotFound
+	}
+	for _, post := range related.([]*Post) {
+		if indexOfPost(blog.Posts, post.ID) < 0 {
+			blog.Posts = append(blog.Posts, post)
+		}
+	}
+	return blog, nil
+}
+
+func (s *blogStore) RemoveFromRelationship(ctx context.Context, id, name string, related interface{}) (interface{}, error) {
+	blog, ok := s.blogs[id]
+	if !ok {
+		return nil, ErrResourceNotFound
+	}
+	for _, post := range related.([]*Post) {
+		if i := indexOfPost(blog.Posts, post.ID); i >= 0 {
+			blog.Posts = append(blog.Posts[:i], blog.Posts[i+1:]...)
+		}
+	}
+	return blog, nil
+}
+
+func indexOfPost(posts []*Post, id uint64) int {
+	for i, post := range posts {
+		if post.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// bookFinder only implements Finder.
+type bookFinder struct{}
+
+func (bookFinder) Find(ctx context.Context, id string, q *Query) (interface{}, error) {
+	return &Book{ID: 1, Title: "Go"}, nil
+}
+
+func testServer(t *testing.T) (*Server, *blogStore) {
+	store := &blogStore{blogs: map[string]*Blog{
+		"1": {
+			ID:    1,
+			Title: "Title 1",
+			Posts: []*Post{{ID: 1, Title: "Post 1"}, {ID: 2, Title: "Post 2"}},
+		},
+		"2": {ID: 2, Title: "Title 2", CurrentPost: &Post{ID: 3, Title: "Post 3"}},
+	}}
+
+	srv := NewServer()
+	if err := srv.Register(new(Blog), store); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Register(new(Book), bookFinder{}); err != nil {
+		t.Fatal(err)
+	}
+	return srv, store
+}
+
+func serve(srv *Server, method, target, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set(headerAccept, MediaType)
+	if body != "" {
+		r.Header.Set(headerContentType, MediaType)
+	}
+	rr := httptest.NewRecorder()
+	srv.ServeHTTP(rr, r)
+	return rr
+}
+
+func TestServer_Register(t *testing.T) {
+	srv, _ := testServer(t)
+
+	if err := srv.Register(new(Blog), &blogStore{}); err == nil {
+		t.Fatal("Was expecting an error registering blogs twice")
+	}
+	if err := srv.Register(new(Post), struct{}{}); err == nil {
+		t.Fatal("Was expecting an error registering a handler without resource methods")
+	}
+	if err := srv.Register(Post{}, bookFinder{}); err != ErrUnexpectedType {
+		t.Fatalf("Was expecting ErrUnexpectedType, got %v", err)
+	}
+}
+
+func TestServer_list(t *testing.T) {
+	srv, _ := testServer(t)
+
+	rr := serve(srv, http.MethodGet, "/blogs", "")
+	if e, a := http.StatusOK, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+	blogs, err := UnmarshalManyPayload(rr.Body, reflect.TypeOf(new(Blog)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 2, len(blogs); e != a {
+		t.Fatalf("Was expecting %d blogs, got %d", e, a)
+	}
+}
+
+func TestServer_find(t *testing.T) {
+	srv, _ := testServer(t)
+
+	rr := serve(srv, http.MethodGet, "/blogs/1?include=posts&fields[posts]=title", "")
+	if e, a := http.StatusOK, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+	payload := new(OnePayload)
+	if err := json.NewDecoder(rr.Body).Decode(payload); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 2, len(payload.Included); e != a {
+		t.Fatalf("Was expecting %d included posts, got %d", e, a)
+	}
+	if _, ok := payload.Included[0].Attributes["body"]; ok {
+		t.Fatal("Was expecting the sparse fieldset to be applied")
+	}
+
+	if e, a := http.StatusNotFound, serve(srv, http.MethodGet, "/blogs/9", "").Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+	if e, a := http.StatusNotFound, serve(srv, http.MethodGet, "/comments/1", "").Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+	if e, a := http.StatusBadRequest, serve(srv, http.MethodGet, "/blogs/1?include=authors", "").Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+}
+
+func TestServer_create(t *testing.T) {
+	srv, store := testServer(t)
+
+	rr := serve(srv, http.MethodPost, "/blogs",
+		`{"data": {"type": "blogs", "attributes": {"title": "New"}}}`)
+	if e, a := http.StatusCreated, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d: %s", e, a, rr.Body)
+	}
+	if e, a := "https://example.com/api/blogs/3", rr.Header().Get(headerLocation); e != a {
+		t.Fatalf("Was expecting a Location of %q, got %q", e, a)
+	}
+	if e, a := "New", store.blogs["3"].Title; e != a {
+		t.Fatalf("Was expecting the title %q, got %q", e, a)
+	}
+
+	rr = serve(srv, http.MethodPost, "/blogs", `{"data": {"type": "posts"}}`)
+	if e, a := http.StatusConflict, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+
+	rr = serve(srv, http.MethodPost, "/blogs",
+		`{"data": {"type": "blogs", "attributes": {"title": 1}}}`)
+	errs, err := UnmarshalErrors(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs[0].Source == nil || errs[0].Source.Pointer != "/data/attributes/title" {
+		t.Fatalf("Was expecting an error at /data/attributes/title, got %v", errs)
+	}
+}
+
+func TestServer_update(t *testing.T) {
+	srv, store := testServer(t)
+
+	rr := serve(srv, http.MethodPatch, "/blogs/1",
+		`{"data": {"type": "blogs", "id": "1", "attributes": {"title": "Updated"}}}`)
+	if e, a := http.StatusOK, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d: %s", e, a, rr.Body)
+	}
+	blog := store.blogs["1"]
+	if e, a := "Updated", blog.Title; e != a {
+		t.Fatalf("Was expecting the title %q, got %q", e, a)
+	}
+	if e, a := 2, len(blog.Posts); e != a {
+		t.Fatalf("Was expecting the absent posts to be kept, got %d", a)
+	}
+
+	rr = serve(srv, http.MethodPatch, "/blogs/1", `{"data": {"type": "blogs", "id": "2"}}`)
+	if e, a := http.StatusConflict, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+
+	rr = serve(srv, http.MethodPatch, "/blogs/1", `{"data": {"type": "blogs", "attributes": {"title": "No id"}}}`)
+	if e, a := http.StatusBadRequest, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d for a resource without an id, got %d", e, a)
+	}
+	if e, a := "Updated", store.blogs["1"].Title; e != a {
+		t.Fatalf("Was expecting the title %q to be kept, got %q", e, a)
+	}
+}
+
+func TestServer_delete(t *testing.T) {
+	srv, store := testServer(t)
+
+	rr := serve(srv, http.MethodDelete, "/blogs/2", "")
+	if e, a := http.StatusNoContent, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+	if a := rr.Header().Get(headerContentType); a != "" {
+		t.Fatalf("Was expecting no Content-Type, got %q", a)
+	}
+	if _, ok := store.blogs["2"]; ok {
+		t.Fatal("Was expecting the blog to be deleted")
+	}
+}
+
+func TestServer_negotiated(t *testing.T) {
+	profile := "http://example.com/profiles/timestamps"
+	srv := NewServer(NegotiateProfiles(profile))
+	if err := srv.Register(new(Book), bookFinder{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{"/books/1", "/books/1/missing"} {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set(headerAccept, MediaType+`; profile="`+profile+`"`)
+		rr := httptest.NewRecorder()
+		srv.ServeHTTP(rr, r)
+
+		if e, a := MediaType+`; profile="`+profile+`"`, rr.Header().Get(headerContentType); e != a {
+			t.Fatalf("Was expecting a Content-Type of %q for %s, got %q", e, target, a)
+		}
+	}
+}
+
+func TestServer_related(t *testing.T) {
+	srv, _ := testServer(t)
+
+	rr := serve(srv, http.MethodGet, "/blogs/1/posts", "")
+	if e, a := http.StatusOK, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+	posts, err := UnmarshalManyPayload(rr.Body, reflect.TypeOf(new(Post)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 2, len(posts); e != a {
+		t.Fatalf("Was expecting %d posts, got %d", e, a)
+	}
+
+	rr = serve(srv, http.MethodGet, "/blogs/1/current_post", "")
+	if ok, err := isJSONEqual([]byte(`{"data":null}`), rr.Body.Bytes()); err != nil || !ok {
+		t.Fatalf("Was expecting null data, got %s", rr.Body)
+	}
+
+	rr = serve(srv, http.MethodGet, "/blogs/2/posts", "")
+	if ok, err := isJSONEqual([]byte(`{"data":[]}`), rr.Body.Bytes()); err != nil || !ok {
+		t.Fatalf("Was expecting empty data, got %s", rr.Body)
+	}
+
+	if e, a := http.StatusNotFound, serve(srv, http.MethodGet, "/blogs/1/authors", "").Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+}
+
+func TestServer_relationship(t *testing.T) {
+	srv, store := testServer(t)
+
+	rr := serve(srv, http.MethodGet, "/blogs/2/relationships/current_post", "")
+	linkage, err := UnmarshalLinkage(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linkage.IsMany || len(linkage.Data) != 1 || linkage.Data[0].ID != "3" {
+		t.Fatalf("Was expecting the linkage of post 3, got %#v", linkage)
+	}
+
+	rr = serve(srv, http.MethodPatch, "/blogs/1/relationships/posts",
+		`{"data": [{"type": "posts", "id": "5"}]}`)
+	if e, a := http.StatusOK, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d: %s", e, a, rr.Body)
+	}
+	blog := store.blogs["1"]
+	if len(blog.Posts) != 1 || blog.Posts[0].ID != 5 {
+		t.Fatalf("Was expecting the posts to be replaced, got %#v", blog.Posts)
+	}
+	if e, a := "Title 1", blog.Title; e != a {
+		t.Fatalf("Was expecting the title %q to be kept, got %q", e, a)
+	}
+}
+
+func TestServer_changeRelationship(t *testing.T) {
+	srv, store := testServer(t)
+
+	postIDs := func() []uint64 {
+		var ids []uint64
+		for _, post := range store.blogs["1"].Posts {
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+
+	rr := serve(srv, http.MethodPost, "/blogs/1/relationships/posts",
+		`{"data": [{"type": "posts", "id": "2"}, {"type": "posts", "id": "5"}]}`)
+	if e, a := http.StatusOK, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d: %s", e, a, rr.Body)
+	}
+	if e, a := []uint64{1, 2, 5}, postIDs(); !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the posts %v, got %v", e, a)
+	}
+	linkage, err := UnmarshalLinkage(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 3, len(linkage.Data); !linkage.IsMany || e != a {
+		t.Fatalf("Was expecting the linkage of %d posts, got %#v", e, linkage)
+	}
+
+	rr = serve(srv, http.MethodDelete, "/blogs/1/relationships/posts",
+		`{"data": [{"type": "posts", "id": "1"}, {"type": "posts", "id": "7"}]}`)
+	if e, a := http.StatusOK, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d: %s", e, a, rr.Body)
+	}
+	if e, a := []uint64{2, 5}, postIDs(); !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the posts %v, got %v", e, a)
+	}
+
+	rr = serve(srv, http.MethodPost, "/blogs/2/relationships/current_post",
+		`{"data": [{"type": "posts", "id": "5"}]}`)
+	if e, a := http.StatusForbidden, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d for a to-one relationship, got %d", e, a)
+	}
+
+	rr = serve(srv, http.MethodDelete, "/blogs/9/relationships/posts",
+		`{"data": [{"type": "posts", "id": "1"}]}`)
+	if e, a := http.StatusNotFound, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+}
+
+func TestServer_methodNotAllowed(t *testing.T) {
+	srv, _ := testServer(t)
+
+	rr := serve(srv, http.MethodDelete, "/books/1", "")
+	if e, a := http.StatusMethodNotAllowed, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+	if e, a := http.MethodGet, rr.Header().Get("Allow"); e != a {
+		t.Fatalf("Was expecting Allow to be %q, got %q", e, a)
+	}
+
+	rr = serve(srv, http.MethodPost, "/books/1/relationships/author", `{"data": []}`)
+	if e, a := http.StatusMethodNotAllowed, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+	if e, a := http.MethodGet, rr.Header().Get("Allow"); e != a {
+		t.Fatalf("Was expecting Allow to be %q, got %q", e, a)
+	}
+
+	rr = serve(srv, http.MethodGet, "/books", "")
+	if e, a := http.StatusMethodNotAllowed, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+
+	errs, err := UnmarshalErrors(rr.Body)
+	if err != nil || len(errs) != 1 {
+		t.Fatalf("Was expecting an errors document, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+const headerLocation = "Location"
+
+// WriteResource writes models, a struct pointer or a slice of them as given to
+// MarshalPayload, as the response with the given status:
+//
+//	jsonapi.WriteResource(w, http.StatusCreated, blog, query.MarshalOptions()...)
+//
+// The Content-Type is the JSON API media type, without the extensions and
+// profiles negotiated by Negotiate; use Negotiation.WriteResource to apply
+// them. A Content-Type the handler has already set to the JSON API media type
+// with parameters is kept. A 201 Created response has a Location header with
+// the "self" link of the created resource, if it has one.
+//
+// The payload is marshaled before anything is written, so that if marshaling
+// fails the response is a clean errors document rather than a truncated one:
+// the *ErrorObject of an invalid include path, or else 500 Internal Server
+// Error. The error is also returned.
+func WriteResource(w http.ResponseWriter, status int, models interface{}, opts ...MarshalOption) error {
+	payload, err := Marshal(models, opts...)
+	if err != nil {
+		if e, ok := err.(*ErrorObject); ok {
+			WriteErrors(w, []*ErrorObject{e})
+		} else {
+			writeInternalError(w)
+		}
+		return err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(payload); err != nil {
+		writeInternalError(w)
+		return err
+	}
+
+	if one, ok := payload.(*OnePayload); ok && status == http.StatusCreated && one.Data != nil {
+		if one.Data.Links != nil {
+			if self := linkHref((*one.Data.Links)[KeySelfLink]); self != "" {
+				w.Header().Set(headerLocation, self)
+			}
+		}
+	}
+
+	setContentType(w)
+	w.WriteHeader(status)
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// WriteErrors writes errs as an errors document. The status of the response is
+// that of the error objects if they all have the same one; otherwise it is 400
+// Bad Request for client errors, or 500 Internal Server Error if any is a
+// server error or none has a status. The Content-Type is set as by
+// WriteResource; use Negotiation.WriteErrors to apply the negotiated
+// extensions and profiles.
+func WriteErrors(w http.ResponseWriter, errs []*ErrorObject) error {
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalErrors(buf, errs); err != nil {
+		writeInternalError(w)
+		return err
+	}
+
+	setContentType(w)
+	w.WriteHeader(errorsStatusCode(errs))
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// WriteResource writes models as WriteResource does, with the media type of n,
+// including the negotiated extensions and profiles, as the Content-Type:
+//
+//	n := jsonapi.NegotiationFromContext(r.Context())
+//	n.WriteResource(w, http.StatusOK, blog)
+//
+// A nil Negotiation writes the JSON API media type without parameters.
+func (n *Negotiation) WriteResource(w http.ResponseWriter, status int, models interface{}, opts ...MarshalOption) error {
+	w.Header().Set(headerContentType, n.MediaType())
+	return WriteResource(w, status, models, opts...)
+}
+
+// WriteErrors writes errs as WriteErrors does, with the media type of n as the
+// Content-Type.
+func (n *Negotiation) WriteErrors(w http.ResponseWriter, errs []*ErrorObject) error {
+	w.Header().Set(headerContentType, n.MediaType())
+	return WriteErrors(w, errs)
+}
+
+// errorsStatusCode returns the status of a response with the errors errs.
+func errorsStatusCode(errs []*ErrorObject) int {
+	var status int
+	for _, e := range errs {
+		switch code := e.StatusCode(); {
+		case code == 0 || code == status:
+		case status == 0:
+			status = code
+		case code >= http.StatusInternalServerError || status >= http.StatusInternalServerError:
+			status = http.StatusInternalServerError
+		default:
+			status = http.StatusBadRequest
+		}
+	}
+	if status == 0 {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+// writeInternalError writes a 500 errors document, for a response that could
+// not be marshaled.
+func writeInternalError(w http.ResponseWriter) {
+	setContentType(w)
+	w.WriteHeader(http.StatusInternalServerError)
+	MarshalErrors(w, []*ErrorObject{{
+		Title:  http.StatusText(http.StatusInternalServerError),
+		Status: "500",
+	}})
+}
+
+// setContentType sets the Content-Type of the response to the JSON API media
+// type, unless it already is, as with the parameters of Negotiation.MediaType.
+func setContentType(w http.ResponseWriter) {
+	if mediaType, _, err := mime.ParseMediaType(w.Header().Get(headerContentType)); err == nil && mediaType == MediaType {
+		return
+	}
+	w.Header().Set(headerContentType, MediaType)
+}
+
+// linkHref returns the URL of a link, which is a string or a link object,
+// including one decoded from JSON.
+func linkHref(link interface{}) string {
+	switch l := link.(type) {
+	case string:
+		return l
+	case map[string]interface{}:
+		href, _ := l["href"].(string)
+		return href
+	case Link:
+		return l.Href
+	case *Link:
+		if l != nil {
+			return l.Href
+		}
+	}
+	return ""
+}
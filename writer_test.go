@@ -0,0 +1,147 @@
+package jsonapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWriteResource(t *testing.T) {
+	rr := httptest.NewRecorder()
+	if err := WriteResource(rr, http.StatusCreated, &Blog{ID: 5, Title: "Title 1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, a := http.StatusCreated, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+	if e, a := MediaType, rr.Header().Get(headerContentType); e != a {
+		t.Fatalf("Was expecting a Content-Type of %q, got %q", e, a)
+	}
+	if e, a := "https://example.com/api/blogs/5", rr.Header().Get(headerLocation); e != a {
+		t.Fatalf("Was expecting a Location of %q, got %q", e, a)
+	}
+
+	out := new(Blog)
+	if err := UnmarshalPayload(rr.Body, out); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := "Title 1", out.Title; e != a {
+		t.Fatalf("Was expecting a title of %q, got %q", e, a)
+	}
+}
+
+func TestWriteResource_negotiated(t *testing.T) {
+	handler := Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NegotiationFromContext(r.Context()).WriteResource(w, http.StatusOK, []*Blog{{ID: 5}})
+	}), NegotiateExtensions(AtomicExtension))
+
+	r := httptest.NewRequest(http.MethodGet, "/blogs", nil)
+	r.Header.Set(headerAccept, MediaType+`; ext="`+AtomicExtension+`"`)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+
+	if e, a := MediaType+`; ext="`+AtomicExtension+`"`, rr.Header().Get(headerContentType); e != a {
+		t.Fatalf("Was expecting a Content-Type of %q, got %q", e, a)
+	}
+	if a := rr.Header().Get(headerLocation); a != "" {
+		t.Fatalf("Was expecting no Location, got %q", a)
+	}
+}
+
+func TestWriteErrors_negotiated(t *testing.T) {
+	profile := "http://example.com/profiles/timestamps"
+	handler := Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NegotiationFromContext(r.Context()).WriteErrors(w, []*ErrorObject{{Status: "409"}})
+	}), NegotiateProfiles(profile))
+
+	r := httptest.NewRequest(http.MethodGet, "/blogs", nil)
+	r.Header.Set(headerAccept, MediaType+`; profile="`+profile+`"`)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, r)
+
+	if e, a := MediaType+`; profile="`+profile+`"`, rr.Header().Get(headerContentType); e != a {
+		t.Fatalf("Was expecting a Content-Type of %q, got %q", e, a)
+	}
+	if e, a := http.StatusConflict, rr.Code; e != a {
+		t.Fatalf("Was expecting status %d, got %d", e, a)
+	}
+}
+
+func TestRuntime_WriteResource(t *testing.T) {
+	events, restore := recordEvents()
+	defer restore()
+
+	rr := httptest.NewRecorder()
+	if err := NewRuntime().WriteResource(rr, http.StatusOK, &Blog{ID: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := http.StatusOK, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+	if e, a := MediaType, rr.Header().Get(headerContentType); e != a {
+		t.Fatalf("Was expecting a Content-Type of %q, got %q", e, a)
+	}
+	if e, a := []Event{MarshalStart, MarshalStop}, *events; !reflect.DeepEqual(e, a) {
+		t.Fatalf("Was expecting the events %v, got %v", e, a)
+	}
+
+	out := new(Blog)
+	if err := UnmarshalPayload(rr.Body, out); err != nil {
+		t.Fatal(err)
+	}
+	if e, a := 5, out.ID; e != a {
+		t.Fatalf("Was expecting blog %d, got %d", e, a)
+	}
+}
+
+func TestWriteResource_marshalError(t *testing.T) {
+	rr := httptest.NewRecorder()
+	if err := WriteResource(rr, http.StatusOK, &BadModel{ID: 1}); err == nil {
+		t.Fatal("Was expecting an error")
+	}
+
+	if e, a := http.StatusInternalServerError, rr.Code; e != a {
+		t.Fatalf("Was expecting a status of %d, got %d", e, a)
+	}
+	errs, err := UnmarshalErrors(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs[0].Status != "500" {
+		t.Fatalf("Was expecting a single 500 error object, got %v", errs)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		status   int
+	}{
+		{[]string{"422", "422"}, http.StatusUnprocessableEntity},
+		{[]string{"404", "422"}, http.StatusBadRequest},
+		{[]string{"422", "503", "400"}, http.StatusInternalServerError},
+		{[]string{"", "409"}, http.StatusConflict},
+		{[]string{""}, http.StatusInternalServerError},
+		{nil, http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		var errs []*ErrorObject
+		for _, status := range test.statuses {
+			errs = append(errs, &ErrorObject{Title: "Error", Status: status})
+		}
+
+		rr := httptest.NewRecorder()
+		if err := WriteErrors(rr, errs); err != nil {
+			t.Fatal(err)
+		}
+		if e, a := test.status, rr.Code; e != a {
+			t.Fatalf("Was expecting a status of %d for %v, got %d", e, test.statuses, a)
+		}
+		if e, a := MediaType, rr.Header().Get(headerContentType); e != a {
+			t.Fatalf("Was expecting a Content-Type of %q, got %q", e, a)
+		}
+	}
+}